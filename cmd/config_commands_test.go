@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
 )
 
 // TestConfigListCommand_Execute_ShouldListAllAPIs tests the config list functionality
@@ -234,6 +235,47 @@ active_api = "api1"
 	assert.Contains(t, api2Line, "active")
 }
 
+func TestConfigSwitchCommand_Execute_WithActiveGroup_ShouldWarn(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test.toml")
+
+	testConfig := `[server]
+port = 8080
+
+[[apis]]
+id = "api1"
+name = "API One"
+url = "https://api1.com"
+
+[[apis]]
+id = "api2"
+name = "API Two"
+url = "https://api2.com"
+
+[settings]
+active_api = "api1"
+active_group = [{ id = "api1" }, { id = "api2" }]
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0644))
+
+	stateManager := createTestStateManager(t)
+	cmd := newConfigSwitchCommand(&configFile, stateManager)
+	cmd.SetArgs([]string{"api2"})
+
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	// Act
+	err := cmd.Execute()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "Switched to API: api2")
+	assert.Contains(t, output.String(), "Warning: active_group is set")
+}
+
 func TestConfigSwitchCommand_Execute_WithNonExistentAPI_ShouldReturnError(t *testing.T) {
 	// Arrange
 	tempDir := t.TempDir()
@@ -269,6 +311,72 @@ active_api = "api1"
 	assert.Contains(t, outputStr, "not found")
 }
 
+func TestConfigSwitchCommand_Execute_WithAgent_ShouldSwitchOnlyThatAgent(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test.toml")
+
+	testConfig := `[server]
+port = 8080
+
+[[apis]]
+id = "api1"
+name = "API One"
+url = "https://api1.com"
+
+[[apis]]
+id = "api2"
+name = "API Two"
+url = "https://api2.com"
+
+[settings]
+active_api = "api1"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0644))
+
+	stateManager := createTestStateManager(t)
+	cmd := newConfigSwitchCommand(&configFile, stateManager)
+	cmd.SetArgs([]string{"--agent", "codex", "api2"})
+
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	// Act
+	err := cmd.Execute()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "Switched agent 'codex' to API: api2")
+
+	cfg, err := config.NewManager(configFile).LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "api1", cfg.Settings.ActiveAPI)
+	assert.Equal(t, map[string]string{"codex": "api2"}, cfg.Settings.AgentAPIs)
+}
+
+func TestConfigSwitchCommand_Execute_WithUnknownAgent_ShouldReturnError(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test.toml")
+	require.NoError(t, os.WriteFile(configFile, []byte("[[apis]]\nid = \"api1\"\n"), 0644))
+
+	stateManager := createTestStateManager(t)
+	cmd := newConfigSwitchCommand(&configFile, stateManager)
+	cmd.SetArgs([]string{"--agent", "cursor", "api1"})
+
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	// Act
+	err := cmd.Execute()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "unknown agent")
+}
+
 // TestConfigRemoveCommand_Execute_ShouldRemoveAPI tests removing an API
 func TestConfigRemoveCommand_Execute_ShouldRemoveAPI(t *testing.T) {
 	// Arrange
@@ -402,3 +510,94 @@ active_api = "api1"
 	assert.Contains(t, outputStr, "Retry Count: 3")
 	assert.Contains(t, outputStr, "Status: Active")
 }
+
+func TestRouteExplainCommand_Execute_ShouldShowMatchingUpstream(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test.toml")
+
+	testConfig := `[server]
+port = 8080
+
+[[apis]]
+id = "official"
+name = "Official"
+url = "https://api.anthropic.com"
+
+[[apis]]
+id = "reseller"
+name = "Reseller"
+url = "https://cheap.example.com"
+
+[settings]
+active_api = "official"
+
+[[model_routes]]
+model = "claude-haiku-*"
+api = "reseller"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0644))
+
+	tests := []struct {
+		model    string
+		expected []string
+	}{
+		{"claude-haiku-4-5", []string{"Matched: model rule 'claude-haiku-*'", "Upstream: reseller (https://cheap.example.com)"}},
+		{"claude-opus-4-1", []string{"Matched: active API", "Upstream: official (https://api.anthropic.com)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			stateManager := createTestStateManager(t)
+			cmd := newRouteExplainCommand(&configFile, stateManager)
+			cmd.SetArgs([]string{"--model", tt.model})
+
+			var output bytes.Buffer
+			cmd.SetOut(&output)
+			cmd.SetErr(&output)
+
+			// Act
+			err := cmd.Execute()
+
+			// Assert
+			require.NoError(t, err)
+			for _, expected := range tt.expected {
+				assert.Contains(t, output.String(), expected)
+			}
+		})
+	}
+}
+
+func TestTLSTrustInfoCommand_Execute_ShouldGenerateAndShowLocalCA(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	configFile := filepath.Join(tempDir, "test.toml")
+
+	testConfig := `[server]
+port = 8443
+
+[server.tls]
+enabled = true
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0644))
+
+	stateManager := createTestStateManager(t)
+	cmd := newTLSTrustInfoCommand(&configFile, stateManager)
+
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	// Act
+	err := cmd.Execute()
+
+	// Assert
+	require.NoError(t, err)
+	caFile := filepath.Join(config.GetDefaultPathManager().TLSDir(), "ca.pem")
+	assert.FileExists(t, caFile)
+	assert.Contains(t, output.String(), "Local CA: "+caFile)
+	assert.Contains(t, output.String(), "SHA-256 Fingerprint: ")
+	assert.Contains(t, output.String(), "NODE_EXTRA_CA_CERTS=")
+	assert.Contains(t, output.String(), "https://127.0.0.1:8443/v1/models")
+}
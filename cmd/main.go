@@ -5,16 +5,20 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"octopus-cli/internal/config"
+	"octopus-cli/internal/proxy"
 	"octopus-cli/internal/state"
 	"octopus-cli/internal/utils"
 )
@@ -136,6 +140,11 @@ func runDaemon() {
 		os.Exit(1)
 	}
 
+	// Publish the admin endpoint so CLI commands can apply changes live
+	if err := serviceManager.processManager.WriteAdminInfo(serviceManager.proxyServer.AdminAddr(), serviceManager.proxyServer.AdminToken()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to write admin file: %v\n", err)
+	}
+
 	// Keep daemon running
 	select {}
 }
@@ -185,7 +194,10 @@ func autoStartService(configFile string) error {
 
 	if status.IsRunning {
 		fmt.Println(utils.FormatSuccess("✅ Service already running") + utils.FormatDim(fmt.Sprintf(" (PID: %d)", status.PID)))
-		fmt.Println(utils.FormatInfo("🌐 Proxy available at:") + " " + utils.FormatHighlight(fmt.Sprintf("http://localhost:%d", cfg.Server.Port)))
+		fmt.Println(utils.FormatInfo("🌐 Proxy available at:") + " " + utils.FormatHighlight(proxyURL(cfg.Server)))
+		if warning := exposureWarning(cfg.Server); warning != "" {
+			fmt.Println(utils.FormatWarning(warning))
+		}
 		return nil
 	}
 
@@ -197,7 +209,10 @@ func autoStartService(configFile string) error {
 	}
 
 	fmt.Println(utils.FormatSuccess("✅ Service started successfully!"))
-	fmt.Println(utils.FormatInfo("🌐 Proxy available at:") + " " + utils.FormatHighlight(fmt.Sprintf("http://localhost:%d", cfg.Server.Port)))
+	fmt.Println(utils.FormatInfo("🌐 Proxy available at:") + " " + utils.FormatHighlight(proxyURL(cfg.Server)))
+	if warning := exposureWarning(cfg.Server); warning != "" {
+		fmt.Println(utils.FormatWarning(warning))
+	}
 	fmt.Println(utils.FormatInfo("📊 Active API:") + " " + utils.FormatBold(activeAPI.Name) + utils.FormatDim(" -> ") + utils.FormatDim(activeAPI.URL))
 
 	return nil
@@ -315,6 +330,8 @@ restarting Claude Code or modifying environment variables.`,
 	rootCmd.AddCommand(newConfigCommand(&configFile, stateManager))
 	rootCmd.AddCommand(newHealthCommand(&configFile, stateManager))
 	rootCmd.AddCommand(newLogsCommand(&configFile, stateManager))
+	rootCmd.AddCommand(newRouteCommand(&configFile, stateManager))
+	rootCmd.AddCommand(newTLSCommand(&configFile, stateManager))
 	rootCmd.AddCommand(newUpgradeCommand(&configFile, version))
 
 	return rootCmd
@@ -368,6 +385,11 @@ func newStartCommand(configFile *string, stateManager *state.Manager) *cobra.Com
 			}
 
 			cmd.Println("Service started successfully")
+			if cfg, err := serviceManager.configManager.LoadConfig(); err == nil {
+				if warning := exposureWarning(cfg.Server); warning != "" {
+					cmd.Println(utils.FormatWarning(warning))
+				}
+			}
 			return nil
 		},
 	}
@@ -447,7 +469,25 @@ func newStatusCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 				cmd.Printf("Status: Stopped\n")
 			}
 
-			cmd.Printf("Port: %d\n", status.Port)
+			server := serviceManager.configManager.GetConfig().Server
+			if len(server.Listen) > 0 {
+				cmd.Printf("Listen:\n")
+				for _, listener := range status.Listeners {
+					cmd.Printf("  %s\n", listener)
+				}
+			} else {
+				cmd.Printf("Host: %s\n", status.Host)
+				cmd.Printf("Port: %d\n", status.Port)
+			}
+			if server.ClientAuthEnabled() {
+				cmd.Printf("Client Auth: %d token(s)\n", len(server.ClientTokens))
+			}
+			if server.TLS.Enabled {
+				cmd.Printf("URL: %s\n", proxyURL(server))
+			}
+			if warning := exposureWarning(server); warning != "" {
+				cmd.Println(utils.FormatWarning(warning))
+			}
 
 			if status.ActiveAPI != "" {
 				cmd.Printf("Active API: %s\n", status.ActiveAPI)
@@ -455,11 +495,116 @@ func newStatusCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 				cmd.Printf("Active API: (none configured)\n")
 			}
 
+			// Show circuit breaker state reported by the running daemon
+			if status.IsRunning {
+				if breakers := serviceManager.LiveBreakers(); len(breakers) > 0 {
+					cmd.Printf("Circuit Breakers:\n")
+					ids := make([]string, 0, len(breakers))
+					for id := range breakers {
+						ids = append(ids, id)
+					}
+					sort.Strings(ids)
+					for _, id := range ids {
+						cmd.Printf("  %s: %s\n", id, formatBreaker(breakers[id]))
+					}
+				}
+				if queues := serviceManager.LiveQueues(); len(queues) > 0 {
+					cmd.Printf("Rate Limit Queues:\n")
+					ids := make([]string, 0, len(queues))
+					for id := range queues {
+						ids = append(ids, id)
+					}
+					sort.Strings(ids)
+					for _, id := range ids {
+						cmd.Printf("  %s: %d waiting\n", id, queues[id])
+					}
+				}
+			}
+
 			return nil
 		},
 	}
 }
 
+// proxyURL returns the addresses local agents reach the proxy at
+func proxyURL(server config.ServerConfig) string {
+	listeners, err := server.Listeners()
+	if err != nil {
+		return fmt.Sprintf("(%v)", err)
+	}
+	urls := make([]string, len(listeners))
+	for i, listener := range listeners {
+		urls[i] = listenerURL(server, listener)
+	}
+	return strings.Join(urls, ", ")
+}
+
+// listenerURL returns the URL of a listener, showing wildcard hosts as
+// localhost
+func listenerURL(server config.ServerConfig, listener config.Listener) string {
+	if listener.Network == "unix" {
+		return listener.String()
+	}
+	return server.Scheme() + "://" + dialAddress(listener)
+}
+
+// dialAddress returns the address to connect to a listener at
+func dialAddress(listener config.Listener) string {
+	host, port, err := net.SplitHostPort(listener.Address)
+	if err != nil {
+		return listener.Address
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// checkListener reports whether the proxy accepts connections on listener
+func checkListener(listener config.Listener) error {
+	conn, err := net.DialTimeout(listener.Network, dialAddress(listener), 2*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// exposureWarning explains the risk of a proxy that other machines can
+// reach without client authentication, or returns "" when it is local only
+// or requires client tokens
+func exposureWarning(server config.ServerConfig) string {
+	listeners, err := server.Listeners()
+	if err != nil || server.ClientAuthEnabled() {
+		return ""
+	}
+	var exposed []string
+	for _, listener := range listeners {
+		if !listener.IsLoopback() {
+			exposed = append(exposed, listener.Address)
+		}
+	}
+	if len(exposed) == 0 {
+		return ""
+	}
+
+	fix := fmt.Sprintf("Set host = \"%s\" under [server]", config.DefaultHost)
+	if len(server.Listen) > 0 {
+		fix = "Listen on loopback or unix:// addresses only"
+	}
+	return fmt.Sprintf("⚠️  WARNING: the proxy listens on %s without client authentication. "+
+		"Anyone who can reach this address can spend your API keys. "+
+		"%s unless this is intended.", strings.Join(exposed, ", "), fix)
+}
+
+// formatBreaker describes a circuit breaker state for display
+func formatBreaker(breaker proxy.BreakerStatus) string {
+	if breaker.State == proxy.BreakerClosed && breaker.ConsecutiveFailures == 0 {
+		return breaker.State
+	}
+	return fmt.Sprintf("%s (%d consecutive failures, %d/%d failed)",
+		breaker.State, breaker.ConsecutiveFailures, breaker.Failures, breaker.Requests)
+}
+
 func newHealthCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
 	return &cobra.Command{
 		Use:   "health",
@@ -485,6 +630,24 @@ func newHealthCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 				return err
 			}
 
+			// Check that a running proxy accepts connections on every listener
+			if serviceManager, err := NewServiceManager(cfgPath); err == nil {
+				if status, err := serviceManager.Status(); err == nil && status.IsRunning {
+					listeners, err := cfg.Server.Listeners()
+					if err != nil {
+						cmd.Println(utils.FormatError("❌ " + err.Error()))
+					}
+					for _, listener := range listeners {
+						if err := checkListener(listener); err != nil {
+							cmd.Println(utils.FormatError(fmt.Sprintf("❌ Proxy %s: %v", listener, err)))
+						} else {
+							cmd.Println(utils.FormatSuccess(fmt.Sprintf("✅ Proxy %s", listener)))
+						}
+					}
+					cmd.Println()
+				}
+			}
+
 			// Check if there are any APIs to check
 			if len(cfg.APIs) == 0 {
 				cmd.Println(utils.FormatWarning("No APIs configured to check"))
@@ -494,10 +657,16 @@ func newHealthCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 			cmd.Println(utils.FormatBold("Checking API endpoints health..."))
 			cmd.Println()
 
+			// Circuit breaker state is only known to a running daemon
+			var breakers map[string]proxy.BreakerStatus
+			if serviceManager, err := NewServiceManager(cfgPath); err == nil {
+				breakers = serviceManager.LiveBreakers()
+			}
+
 			// Check health of each API endpoint
 			for _, api := range cfg.APIs {
 				// Perform actual connectivity check
-				status, latency := checkAPIHealth(api.URL, api.APIKey)
+				status, latency := checkAPIHealth(api)
 
 				// Determine if healthy based on status
 				isHealthy := status == "✅ Healthy"
@@ -515,6 +684,9 @@ func newHealthCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 				if api.ID == cfg.Settings.ActiveAPI {
 					cmd.Println(utils.FormatHighlight("  Role: [ACTIVE]"))
 				}
+				if breaker, ok := breakers[api.ID]; ok {
+					cmd.Println(utils.FormatDim("  Circuit: " + formatBreaker(breaker)))
+				}
 				cmd.Println()
 			}
 
@@ -627,6 +799,34 @@ func newConfigCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 					} else {
 						cmd.Printf("  Active API: (none configured)\n")
 					}
+					if len(cfg.Settings.ActiveGroup) > 0 {
+						members := make([]string, len(cfg.Settings.ActiveGroup))
+						for i, member := range cfg.Settings.ActiveGroup {
+							members[i] = member.ID
+							if member.Weight > 0 {
+								members[i] = fmt.Sprintf("%s (weight %d)", member.ID, member.Weight)
+							}
+						}
+						strategy := cfg.Settings.BalanceStrategy
+						if strategy == "" {
+							strategy = proxy.BalanceRoundRobin
+						}
+						cmd.Printf("  Active Group: %s [%s]\n", strings.Join(members, ", "), strategy)
+					}
+					if len(cfg.Settings.Fallback) > 0 {
+						cmd.Printf("  Fallback: %s\n", strings.Join(cfg.Settings.Fallback, " -> "))
+					}
+					agents := make([]string, 0, len(cfg.Settings.AgentAPIs))
+					for agent := range cfg.Settings.AgentAPIs {
+						agents = append(agents, agent)
+					}
+					sort.Strings(agents)
+					for _, agent := range agents {
+						cmd.Printf("  Agent API: %s -> %s\n", agent, cfg.Settings.AgentAPIs[agent])
+					}
+					for _, route := range cfg.Routes {
+						cmd.Printf("  Route: %s -> %s\n", route.Path, route.API)
+					}
 					cmd.Printf("  Total APIs: %d\n", len(cfg.APIs))
 				}
 
@@ -644,6 +844,7 @@ func newConfigCommand(configFile *string, stateManager *state.Manager) *cobra.Co
 	configCmd.AddCommand(newConfigSwitchCommand(configFile, stateManager))
 	configCmd.AddCommand(newConfigShowCommand(configFile, stateManager))
 	configCmd.AddCommand(newConfigEditCommand(configFile, stateManager))
+	configCmd.AddCommand(newConfigModelsCommand(configFile, stateManager))
 
 	return configCmd
 }
@@ -710,12 +911,17 @@ func newConfigListCommand(configFile *string, stateManager *state.Manager) *cobr
 }
 
 func newConfigAddCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
-	return &cobra.Command{
+	var authStyle string
+	var protocol string
+	var provider string
+
+	cmd := &cobra.Command{
 		Use:   "add <name> <url> <api-key>",
 		Short: "Add a new API configuration",
 		Args:  cobra.ExactArgs(3),
-		Example: `  octopus config add official https://api.anthropic.com sk-ant-xxx
-  octopus config add proxy1 https://api.proxy1.com pk-xxx`,
+		Example: `  octopus config add official https://api.anthropic.com sk-ant-xxx --auth-style anthropic
+  octopus config add proxy1 https://api.proxy1.com pk-xxx
+  octopus config add deepseek https://api.deepseek.com sk-xxx --protocol openai`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfgPath, _, err := getConfigPath(*configFile, stateManager)
 			if err != nil {
@@ -742,6 +948,9 @@ func newConfigAddCommand(configFile *string, stateManager *state.Manager) *cobra
 				APIKey:     apiKey,
 				Timeout:    30,
 				RetryCount: 3,
+				AuthStyle:  authStyle,
+				Protocol:   protocol,
+				Provider:   provider,
 			}
 
 			// Add the API
@@ -757,9 +966,21 @@ func newConfigAddCommand(configFile *string, stateManager *state.Manager) *cobra
 			}
 
 			cmd.Printf("Added API configuration: %s\n", name)
+
+			// Make the new API available to a running daemon
+			applyToDaemon(cmd, cfgPath, func(client *proxy.AdminClient) error {
+				return client.AddAPI(newAPI)
+			})
+
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&authStyle, "auth-style", "", "How the API key is sent: bearer, anthropic, google, google-query, azure or none (default: bearer)")
+	cmd.Flags().StringVar(&provider, "provider", "", "Upstream vendor: "+strings.Join(proxy.ProviderNames(), ", ")+" (default: inferred from --protocol)")
+	cmd.Flags().StringVar(&protocol, "protocol", "", "API protocol the upstream speaks: anthropic, openai or gemini (default: same as the client)")
+
+	return cmd
 }
 
 func newConfigRemoveCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
@@ -816,19 +1037,31 @@ func newConfigRemoveCommand(configFile *string, stateManager *state.Manager) *co
 				cmd.Printf("Cleared active API\n")
 			}
 
+			// Drop the API from a running daemon
+			applyToDaemon(cmd, cfgPath, func(client *proxy.AdminClient) error {
+				return client.RemoveAPI(name)
+			})
+
 			return nil
 		},
 	}
 }
 
 func newConfigSwitchCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
-	return &cobra.Command{
+	var agent string
+
+	cmd := &cobra.Command{
 		Use:   "switch <name>",
 		Short: "Switch to a specific API configuration",
 		Args:  cobra.ExactArgs(1),
 		Example: `  octopus config switch official
-  octopus config switch proxy1`,
+  octopus config switch proxy1
+  octopus config switch --agent codex openai`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if agent != "" {
+				return switchAgentAPI(cmd, configFile, stateManager, agent, args[0])
+			}
+
 			cfgPath, _, err := getConfigPath(*configFile, stateManager)
 			if err != nil {
 				cmd.Printf("Config error: %v\n", err)
@@ -882,42 +1115,132 @@ func newConfigSwitchCommand(configFile *string, stateManager *state.Manager) *co
 				cmd.Printf("Warning: Failed to log API switch: %v\n", err)
 			}
 
-			// Check if daemon is running and restart it to pick up new configuration
+			// Switch the running daemon in place; fall back to a restart if the
+			// admin endpoint cannot be reached
 			serviceManager, err := NewServiceManager(cfgPath)
 			if err != nil {
 				cmd.Printf("Warning: Failed to create service manager: %v\n", err)
-			} else {
-				status, err := serviceManager.Status()
-				if err != nil {
-					cmd.Printf("Warning: Failed to check service status: %v\n", err)
-				} else if status.IsRunning {
-					cmd.Printf("📝 Restarting daemon to apply new API configuration...\n")
-
-					// Stop the current daemon
-					if err := serviceManager.Stop(); err != nil {
-						cmd.Printf("Warning: Failed to stop daemon: %v\n", err)
-					} else {
-						// Start with new configuration
-						if err := serviceManager.Start(); err != nil {
-							cmd.Printf("Warning: Failed to start daemon with new config: %v\n", err)
-						} else {
-							cmd.Printf("✅ Daemon restarted with new API configuration\n")
-
-							// Log the restart to service log file
-							restartMessage := fmt.Sprintf("Daemon restarted to apply API switch to '%s'", name)
-							if err := logToServiceFile(cfgPath, restartMessage); err != nil {
-								// Don't fail the command if logging fails
-								cmd.Printf("Warning: Failed to log daemon restart: %v\n", err)
-							}
-						}
-					}
-				}
+			} else if applied, err := serviceManager.ApplyLive(func(client *proxy.AdminClient) error {
+				return client.SwitchAPI(name)
+			}); applied {
+				cmd.Printf("✅ Running daemon switched to new API without restart\n")
+			} else if err != nil {
+				cmd.Printf("Warning: Live switch failed (%v), restarting daemon...\n", err)
+				restartDaemonForSwitch(cmd, serviceManager, cfgPath, name)
 			}
 
 			cmd.Printf("Switched to API: %s\n", name)
+			if len(cfg.Settings.ActiveGroup) > 0 {
+				cmd.Printf("Warning: active_group is set, so requests are still balanced across the group; remove active_group from the configuration to use '%s'\n", name)
+			}
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&agent, "agent", "", "switch the API of a single agent only ("+strings.Join(proxy.KnownAgents, ", ")+")")
+	return cmd
+}
+
+// switchAgentAPI assigns an API to a single agent and applies it to the
+// running daemon
+func switchAgentAPI(cmd *cobra.Command, configFile *string, stateManager *state.Manager, agent, name string) error {
+	if !proxy.IsKnownAgent(agent) {
+		err := fmt.Errorf("unknown agent %q, expected one of: %s", agent, strings.Join(proxy.KnownAgents, ", "))
+		cmd.Printf("Error: %v\n", err)
+		return err
+	}
+
+	cfgPath, _, err := getConfigPath(*configFile, stateManager)
+	if err != nil {
+		cmd.Printf("Config error: %v\n", err)
+		return err
+	}
+
+	configManager := config.NewManager(cfgPath)
+	if _, err := configManager.LoadConfig(); err != nil {
+		cmd.Printf("Failed to load configuration: %v\n", err)
+		return err
+	}
+
+	if err := configManager.SetAgentAPI(agent, name); err != nil {
+		cmd.Printf("Failed to switch API: %v\n", err)
+		return err
+	}
+
+	logMessage := fmt.Sprintf("API of agent '%s' switched to '%s'", agent, name)
+	if err := logToServiceFile(cfgPath, logMessage); err != nil {
+		cmd.Printf("Warning: Failed to log API switch: %v\n", err)
+	}
+
+	serviceManager, err := NewServiceManager(cfgPath)
+	if err != nil {
+		cmd.Printf("Warning: Failed to create service manager: %v\n", err)
+	} else if applied, err := serviceManager.ApplyLive(func(client *proxy.AdminClient) error {
+		return client.SwitchAgentAPI(agent, name)
+	}); applied {
+		cmd.Printf("✅ Running daemon switched to new API without restart\n")
+	} else if err != nil {
+		cmd.Printf("Warning: Live switch failed (%v), restarting daemon...\n", err)
+		restartDaemonForSwitch(cmd, serviceManager, cfgPath, name)
+	}
+
+	cmd.Printf("Switched agent '%s' to API: %s\n", agent, name)
+	return nil
+}
+
+// restartDaemonForSwitch restarts the daemon so it picks up a switched API
+func restartDaemonForSwitch(cmd *cobra.Command, serviceManager *ServiceManager, cfgPath, name string) {
+	status, err := serviceManager.Status()
+	if err != nil {
+		cmd.Printf("Warning: Failed to check service status: %v\n", err)
+		return
+	}
+	if !status.IsRunning {
+		return
+	}
+
+	cmd.Printf("📝 Restarting daemon to apply new API configuration...\n")
+
+	// Stop the current daemon
+	if err := serviceManager.Stop(); err != nil {
+		cmd.Printf("Warning: Failed to stop daemon: %v\n", err)
+		return
+	}
+
+	// Start with new configuration
+	if err := serviceManager.Start(); err != nil {
+		cmd.Printf("Warning: Failed to start daemon with new config: %v\n", err)
+		return
+	}
+
+	cmd.Printf("✅ Daemon restarted with new API configuration\n")
+
+	// Log the restart to service log file
+	restartMessage := fmt.Sprintf("Daemon restarted to apply API switch to '%s'", name)
+	if err := logToServiceFile(cfgPath, restartMessage); err != nil {
+		// Don't fail the command if logging fails
+		cmd.Printf("Warning: Failed to log daemon restart: %v\n", err)
+	}
+}
+
+// applyToDaemon applies a configuration change to a running daemon without
+// restarting it. Failures only produce a warning since the file is already saved.
+func applyToDaemon(cmd *cobra.Command, cfgPath string, apply func(client *proxy.AdminClient) error) {
+	serviceManager, err := NewServiceManager(cfgPath)
+	if err != nil {
+		cmd.Printf("Warning: Failed to create service manager: %v\n", err)
+		return
+	}
+
+	applied, err := serviceManager.ApplyLive(apply)
+	if err != nil {
+		cmd.Printf("Warning: Failed to update running daemon: %v\n", err)
+		cmd.Printf("💡 Restart the service to apply the change: octopus stop && octopus start\n")
+		return
+	}
+	if applied {
+		cmd.Printf("✅ Running daemon updated without restart\n")
+	}
 }
 
 func newConfigShowCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
@@ -971,8 +1294,76 @@ func newConfigShowCommand(configFile *string, stateManager *state.Manager) *cobr
 				cmd.Printf("  API Key: %s\n", maskedKey)
 			}
 
+			if targetAPI.AuthStyle != "" {
+				cmd.Printf("  Auth Style: %s\n", targetAPI.AuthStyle)
+			}
+			if targetAPI.Provider != "" {
+				cmd.Printf("  Provider: %s\n", targetAPI.Provider)
+			}
+			if targetAPI.Protocol != "" {
+				cmd.Printf("  Protocol: %s\n", targetAPI.Protocol)
+			}
+			if targetAPI.Region != "" {
+				cmd.Printf("  Region: %s\n", targetAPI.Region)
+			}
+			if targetAPI.AWSProfile != "" {
+				cmd.Printf("  AWS Profile: %s\n", targetAPI.AWSProfile)
+			}
+			if targetAPI.ProjectID != "" {
+				cmd.Printf("  Project: %s\n", targetAPI.ProjectID)
+			}
+			if targetAPI.CredentialsFile != "" {
+				cmd.Printf("  Credentials File: %s\n", targetAPI.CredentialsFile)
+			}
+			if targetAPI.APIVersion != "" {
+				cmd.Printf("  API Version: %s\n", targetAPI.APIVersion)
+			}
+
 			cmd.Printf("  Timeout: %d seconds\n", targetAPI.Timeout)
+			if targetAPI.ConnectTimeout > 0 {
+				cmd.Printf("  Connect Timeout: %d seconds\n", targetAPI.ConnectTimeout)
+			}
+			if targetAPI.FirstByteTimeout > 0 {
+				cmd.Printf("  First Byte Timeout: %d seconds\n", targetAPI.FirstByteTimeout)
+			}
+			if targetAPI.IdleTimeout > 0 {
+				cmd.Printf("  Idle Timeout: %d seconds\n", targetAPI.IdleTimeout)
+			}
 			cmd.Printf("  Retry Count: %d\n", targetAPI.RetryCount)
+			if targetAPI.RequestsPerMinute > 0 {
+				cmd.Printf("  Requests Per Minute: %d\n", targetAPI.RequestsPerMinute)
+			}
+			if targetAPI.InputTokensPerMinute > 0 {
+				cmd.Printf("  Input Tokens Per Minute: %d\n", targetAPI.InputTokensPerMinute)
+			}
+			if targetAPI.MaxConcurrent > 0 {
+				cmd.Printf("  Max Concurrent: %d\n", targetAPI.MaxConcurrent)
+			}
+			if len(targetAPI.ModelMap) > 0 {
+				models := make([]string, 0, len(targetAPI.ModelMap))
+				for model := range targetAPI.ModelMap {
+					models = append(models, model)
+				}
+				sort.Strings(models)
+				cmd.Printf("  Model Map:\n")
+				for _, model := range models {
+					cmd.Printf("    %s -> %s\n", model, targetAPI.ModelMap[model])
+				}
+				if targetAPI.RewriteResponseModel {
+					cmd.Printf("  Rewrite Response Model: yes\n")
+				}
+			}
+			if len(targetAPI.Deployments) > 0 {
+				models := make([]string, 0, len(targetAPI.Deployments))
+				for model := range targetAPI.Deployments {
+					models = append(models, model)
+				}
+				sort.Strings(models)
+				cmd.Printf("  Deployments:\n")
+				for _, model := range models {
+					cmd.Printf("    %s -> %s\n", model, targetAPI.Deployments[model])
+				}
+			}
 
 			// Show if this is the active API
 			if cfg.Settings.ActiveAPI == targetAPI.ID {
@@ -986,6 +1377,57 @@ func newConfigShowCommand(configFile *string, stateManager *state.Manager) *cobr
 	}
 }
 
+func newConfigModelsCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
+	return &cobra.Command{
+		Use:     "models <name>",
+		Short:   "List the models an API serves",
+		Args:    cobra.ExactArgs(1),
+		Example: "  octopus config models official",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfgPath, _, err := getConfigPath(*configFile, stateManager)
+			if err != nil {
+				cmd.Printf("Config error: %v\n", err)
+				return err
+			}
+
+			configManager := config.NewManager(cfgPath)
+			cfg, err := configManager.LoadConfig()
+			if err != nil {
+				cmd.Printf("Failed to load configuration: %v\n", err)
+				return err
+			}
+
+			var targetAPI *config.APIConfig
+			for i := range cfg.APIs {
+				if cfg.APIs[i].ID == args[0] {
+					targetAPI = &cfg.APIs[i]
+					break
+				}
+			}
+			if targetAPI == nil {
+				err := fmt.Errorf("API configuration with ID '%s' not found", args[0])
+				cmd.Printf("Error: %v\n", err)
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			models, err := proxy.ListModels(ctx, &http.Client{Timeout: 10 * time.Second}, targetAPI)
+			if err != nil {
+				cmd.Printf("Error: %v\n", err)
+				return err
+			}
+
+			cmd.Printf("Models served by %s:\n", targetAPI.ID)
+			for _, model := range models {
+				cmd.Printf("  %s\n", model)
+			}
+			return nil
+		},
+	}
+}
+
 func newConfigEditCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
 	var customEditor string
 
@@ -1060,27 +1502,186 @@ func newConfigEditCommand(configFile *string, stateManager *state.Manager) *cobr
 	return cmd
 }
 
-// checkAPIHealth performs a health check on an API endpoint
-func checkAPIHealth(apiURL, apiKey string) (status string, latency time.Duration) {
+func newRouteCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
+	routeCmd := &cobra.Command{
+		Use:   "route",
+		Short: "Inspect request routing",
+		Long:  "Inspect how requests are routed to the configured APIs",
+	}
+
+	routeCmd.AddCommand(newRouteExplainCommand(configFile, stateManager))
+	return routeCmd
+}
+
+func newRouteExplainCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
+	var model, path, agent string
+
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Show which API a request would be sent to",
+		Args:  cobra.NoArgs,
+		Example: `  octopus route explain --model claude-opus-4-1
+  octopus route explain --model gpt-4o --path /v1/chat/completions --agent codex`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !proxy.IsKnownAgent(agent) {
+				err := fmt.Errorf("unknown agent %q, expected one of: %s", agent, strings.Join(proxy.KnownAgents, ", "))
+				cmd.Printf("Error: %v\n", err)
+				return err
+			}
+
+			cfgPath, _, err := getConfigPath(*configFile, stateManager)
+			if err != nil {
+				cmd.Printf("Config error: %v\n", err)
+				return err
+			}
+
+			configManager := config.NewManager(cfgPath)
+			cfg, err := configManager.LoadConfig()
+			if err != nil {
+				cmd.Printf("Failed to load configuration: %v\n", err)
+				return err
+			}
+
+			decision := proxy.ExplainRoute(cfg, path, agent, model)
+
+			cmd.Printf("Request:\n")
+			cmd.Printf("  Model: %s\n", model)
+			cmd.Printf("  Path: %s\n", path)
+			cmd.Printf("  Agent: %s\n", agent)
+			cmd.Printf("Matched: %s\n", decision.Reason)
+
+			if decision.APIID == "" {
+				if len(cfg.Settings.ActiveGroup) == 0 {
+					cmd.Printf("Upstream: (none configured)\n")
+					return nil
+				}
+				members := make([]string, len(cfg.Settings.ActiveGroup))
+				for i, member := range cfg.Settings.ActiveGroup {
+					members[i] = member.ID
+				}
+				cmd.Printf("Upstream: one of %s\n", strings.Join(members, ", "))
+				return nil
+			}
+
+			upstream := decision.APIID
+			for _, api := range cfg.APIs {
+				if api.ID == decision.APIID {
+					upstream = fmt.Sprintf("%s (%s)", api.ID, api.URL)
+					break
+				}
+			}
+			cmd.Printf("Upstream: %s\n", upstream)
+			if decision.StripPrefix != "" {
+				cmd.Printf("  Path forwarded without prefix: %s\n", decision.StripPrefix)
+			}
+			if len(cfg.Settings.Fallback) > 0 {
+				cmd.Printf("Fallback: %s\n", strings.Join(cfg.Settings.Fallback, " -> "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&model, "model", "", "model named in the request body")
+	cmd.Flags().StringVar(&path, "path", "/v1/messages", "request path")
+	cmd.Flags().StringVar(&agent, "agent", proxy.AgentGeneric, "calling agent ("+strings.Join(proxy.KnownAgents, ", ")+")")
+	return cmd
+}
+
+func newTLSCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
+	tlsCmd := &cobra.Command{
+		Use:   "tls",
+		Short: "Manage HTTPS on the proxy listener",
+		Long:  "Manage the certificates the proxy serves when [server.tls] is enabled",
+	}
+
+	tlsCmd.AddCommand(newTLSTrustInfoCommand(configFile, stateManager))
+	return tlsCmd
+}
+
+func newTLSTrustInfoCommand(configFile *string, stateManager *state.Manager) *cobra.Command {
+	return &cobra.Command{
+		Use:   "trust-info",
+		Short: "Show how to trust the local CA",
+		Long:  "Generate the local CA if needed and show how to make the system and agents trust it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfgPath, _, err := getConfigPath(*configFile, stateManager)
+			if err != nil {
+				cmd.Printf("Config error: %v\n", err)
+				return err
+			}
+
+			configManager := config.NewManager(cfgPath)
+			cfg, err := configManager.LoadConfig()
+			if err != nil {
+				cmd.Printf("Failed to load configuration: %v\n", err)
+				return err
+			}
+
+			if cfg.Server.TLS.CertFile != "" {
+				cmd.Printf("The proxy serves the configured certificate %s.\n", cfg.Server.TLS.CertFile)
+				cmd.Printf("Trust the CA that issued it; no local CA is used.\n")
+				return nil
+			}
+
+			// Name the same hosts as the daemon does, so that a certificate
+			// it serves is never reissued without them
+			listen, err := cfg.Server.Listeners()
+			if err != nil {
+				cmd.Printf("Invalid listen configuration: %v\n", err)
+				return err
+			}
+			local, err := proxy.EnsureLocalCertificates(config.GetDefaultPathManager().TLSDir(), proxy.LocalCertificateHosts(listen)...)
+			if err != nil {
+				cmd.Printf("Failed to prepare the local CA: %v\n", err)
+				return err
+			}
+
+			cmd.Printf("Local CA: %s\n", local.CAFile)
+			cmd.Printf("SHA-256 Fingerprint: %s\n", local.CAFingerprint)
+			cmd.Printf("Server Certificate: %s\n", local.CertFile)
+			if !cfg.Server.TLS.Enabled {
+				cmd.Printf("⚠️  TLS is disabled; set enabled = true under [server.tls] to serve HTTPS.\n")
+			}
+
+			cmd.Printf("\nTrust the CA system-wide:\n")
+			switch runtime.GOOS {
+			case "darwin":
+				cmd.Printf("  sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %q\n", local.CAFile)
+			case "windows":
+				cmd.Printf("  certutil -user -addstore Root \"%s\"\n", local.CAFile)
+			default:
+				cmd.Printf("  Debian/Ubuntu: sudo cp %q /usr/local/share/ca-certificates/octopus-ca.crt && sudo update-ca-certificates\n", local.CAFile)
+				cmd.Printf("  Fedora/RHEL:   sudo cp %q /etc/pki/ca-trust/source/anchors/octopus-ca.pem && sudo update-ca-trust\n", local.CAFile)
+			}
+
+			cmd.Printf("\nNode.js agents such as Claude Code and Codex read extra CAs from the environment:\n")
+			cmd.Printf("  export NODE_EXTRA_CA_CERTS=%q\n", local.CAFile)
+			cmd.Printf("\nCheck the proxy with:\n")
+			if listen[0].Network == "tcp" {
+				cmd.Printf("  curl --cacert %q %s/v1/models\n", local.CAFile, listenerURL(cfg.Server, listen[0]))
+			} else {
+				cmd.Printf("  curl --cacert %q --unix-socket %q https://localhost/v1/models\n", local.CAFile, listen[0].Address)
+			}
+			return nil
+		},
+	}
+}
+
+// checkAPIHealth performs a health check on an API endpoint, asking its
+// provider for the endpoint to call and how to authenticate
+func checkAPIHealth(api config.APIConfig) (status string, latency time.Duration) {
 	startTime := time.Now()
 
 	// Create a simple health check request
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	req, _, err := proxy.NewModelsRequest(ctx, &api)
 	if err != nil {
-		return "❌ Invalid URL", 0
-	}
-
-	// Add API key if provided
-	if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return "❌ Invalid configuration", 0
 	}
-
-	// Set proper headers for Anthropic API
 	req.Header.Set("User-Agent", "Octopus-CLI/1.0")
-	req.Header.Set("Accept", "application/json")
 
 	// Create HTTP client with timeout
 	client := &http.Client{
@@ -29,7 +29,7 @@ func TestRootCommand_Subcommands_ShouldHaveAllExpectedCommands(t *testing.T) {
 	cmd := newRootCommand("test", nil)
 	expectedCommands := []string{
 		"version", "start", "stop", "status",
-		"config", "health", "logs",
+		"config", "health", "logs", "route",
 	}
 
 	// Act
@@ -226,6 +226,61 @@ active_api = ""
 	assert.Contains(t, outputStr, "Port: 8080")
 }
 
+func TestStatusCommand_Execute_WithPublicHost_ShouldWarnAboutExposure(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test.toml")
+
+	testConfig := `[server]
+host = "0.0.0.0"
+port = 8080
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0644))
+
+	stateManager := createTestStateManager(t)
+	cmd := newStatusCommand(&configFile, stateManager)
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	// Act
+	err := cmd.Execute()
+
+	// Assert
+	require.NoError(t, err)
+	outputStr := output.String()
+	assert.Contains(t, outputStr, "Host: 0.0.0.0")
+	assert.Contains(t, outputStr, "WARNING: the proxy listens on 0.0.0.0:8080 without client authentication")
+}
+
+func TestStatusCommand_Execute_WithListenList_ShouldShowEveryListener(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test.toml")
+
+	testConfig := `[server]
+port = 8080
+listen = ["unix:///run/octopus/octopus.sock", "tcp://0.0.0.0:9090"]
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0644))
+
+	stateManager := createTestStateManager(t)
+	cmd := newStatusCommand(&configFile, stateManager)
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	// Act
+	err := cmd.Execute()
+
+	// Assert
+	require.NoError(t, err)
+	outputStr := output.String()
+	assert.Contains(t, outputStr, "Listen:\n  unix:///run/octopus/octopus.sock\n  tcp://0.0.0.0:9090\n")
+	assert.NotContains(t, outputStr, "Port: 8080")
+	assert.Contains(t, outputStr, "WARNING: the proxy listens on 0.0.0.0:9090 without client authentication")
+}
+
 func TestStatusCommand_Execute_WithInvalidConfig_ShouldShowError(t *testing.T) {
 	// Arrange
 	invalidConfigFile := "/nonexistent/config.toml"
@@ -147,6 +147,83 @@ func (sm *ServiceManager) Stop() error {
 	return nil
 }
 
+// AdminClient returns a client for the running daemon's admin endpoint
+func (sm *ServiceManager) AdminClient() (*proxy.AdminClient, error) {
+	addr, token, err := sm.processManager.ReadAdminInfo()
+	if err != nil {
+		return nil, fmt.Errorf("admin endpoint not available: %w", err)
+	}
+	return proxy.NewAdminClient(addr, token), nil
+}
+
+// ApplyLive applies a configuration change to the running daemon through its
+// admin endpoint. It returns false without error if the daemon is not running.
+func (sm *ServiceManager) ApplyLive(apply func(client *proxy.AdminClient) error) (bool, error) {
+	status, err := sm.processManager.GetDaemonStatus()
+	if err != nil {
+		return false, fmt.Errorf("failed to check service status: %w", err)
+	}
+	if !status.IsRunning {
+		return false, nil
+	}
+
+	client, err := sm.AdminClient()
+	if err != nil {
+		return false, err
+	}
+	if err := apply(client); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// LiveBreakers returns the circuit breaker state of each API as reported by
+// the running daemon, keyed by API ID. It returns nil if the daemon is not
+// running or cannot be reached.
+func (sm *ServiceManager) LiveBreakers() map[string]proxy.BreakerStatus {
+	var breakers map[string]proxy.BreakerStatus
+	_, err := sm.ApplyLive(func(client *proxy.AdminClient) error {
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		breakers = make(map[string]proxy.BreakerStatus, len(status.Breakers))
+		for _, breaker := range status.Breakers {
+			breakers[breaker.APIID] = breaker
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return breakers
+}
+
+// LiveQueues returns the rate limit queue depth of each rate limited API as
+// reported by the running daemon, keyed by API ID. It returns nil if the
+// daemon is not running or cannot be reached.
+func (sm *ServiceManager) LiveQueues() map[string]int {
+	var queues map[string]int
+	_, err := sm.ApplyLive(func(client *proxy.AdminClient) error {
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		queues = make(map[string]int)
+		for id, upstream := range status.Upstreams {
+			if upstream.Queued > 0 {
+				queues[id] = upstream.Queued
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return queues
+}
+
 // Status returns the current service status
 func (sm *ServiceManager) Status() (*ServiceStatus, error) {
 	cfg, err := sm.configManager.LoadConfig()
@@ -159,6 +236,13 @@ func (sm *ServiceManager) Status() (*ServiceStatus, error) {
 		return nil, fmt.Errorf("failed to get process status: %w", err)
 	}
 
+	var listeners []string
+	if addresses, err := cfg.Server.Listeners(); err == nil {
+		for _, address := range addresses {
+			listeners = append(listeners, address.String())
+		}
+	}
+
 	var proxyStats *proxy.ServerStats
 	if sm.proxyServer.IsRunning() {
 		proxyStats = sm.proxyServer.GetStats()
@@ -167,7 +251,9 @@ func (sm *ServiceManager) Status() (*ServiceStatus, error) {
 	return &ServiceStatus{
 		IsRunning:  processStatus.IsRunning,
 		PID:        processStatus.PID,
+		Host:       cfg.Server.BindHost(),
 		Port:       cfg.Server.Port,
+		Listeners:  listeners,
 		ActiveAPI:  cfg.Settings.ActiveAPI,
 		StartTime:  processStatus.StartTime,
 		Uptime:     processStatus.Uptime,
@@ -179,7 +265,9 @@ func (sm *ServiceManager) Status() (*ServiceStatus, error) {
 type ServiceStatus struct {
 	IsRunning  bool
 	PID        int
+	Host       string
 	Port       int
+	Listeners  []string
 	ActiveAPI  string
 	StartTime  interface{}
 	Uptime     interface{}
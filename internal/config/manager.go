@@ -106,6 +106,46 @@ func (m *Manager) RemoveAPIConfig(id string) error {
 				m.config.Settings.ActiveAPI = ""
 			}
 
+			// Drop it from the fallback chain as well
+			fallback := m.config.Settings.Fallback[:0]
+			for _, fallbackID := range m.config.Settings.Fallback {
+				if fallbackID != id {
+					fallback = append(fallback, fallbackID)
+				}
+			}
+			m.config.Settings.Fallback = fallback
+
+			group := m.config.Settings.ActiveGroup[:0]
+			for _, member := range m.config.Settings.ActiveGroup {
+				if member.ID != id {
+					group = append(group, member)
+				}
+			}
+			m.config.Settings.ActiveGroup = group
+
+			for agent, agentAPI := range m.config.Settings.AgentAPIs {
+				if agentAPI == id {
+					delete(m.config.Settings.AgentAPIs, agent)
+				}
+			}
+
+			// Drop routes to it so matching requests use the active API
+			routes := m.config.Routes[:0]
+			for _, route := range m.config.Routes {
+				if route.API != id {
+					routes = append(routes, route)
+				}
+			}
+			m.config.Routes = routes
+
+			modelRoutes := m.config.ModelRoutes[:0]
+			for _, route := range m.config.ModelRoutes {
+				if route.API != id {
+					modelRoutes = append(modelRoutes, route)
+				}
+			}
+			m.config.ModelRoutes = modelRoutes
+
 			return m.SaveConfig(m.config)
 		}
 	}
@@ -138,6 +178,34 @@ func (m *Manager) SetActiveAPI(id string) error {
 	return m.SaveConfig(m.config)
 }
 
+// SetAgentAPI sets the active API of a single agent
+func (m *Manager) SetAgentAPI(agent, id string) error {
+	if m.config == nil {
+		if _, err := m.LoadConfig(); err != nil {
+			return err
+		}
+	}
+
+	// Verify the API exists
+	found := false
+	for _, api := range m.config.APIs {
+		if api.ID == id {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("API configuration with ID '%s' not found", id)
+	}
+
+	if m.config.Settings.AgentAPIs == nil {
+		m.config.Settings.AgentAPIs = make(map[string]string)
+	}
+	m.config.Settings.AgentAPIs[agent] = id
+	return m.SaveConfig(m.config)
+}
+
 // GetActiveAPI returns the currently active API configuration
 func (m *Manager) GetActiveAPI() (*APIConfig, error) {
 	if m.config == nil {
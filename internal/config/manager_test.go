@@ -293,6 +293,34 @@ func TestManager_RemoveAPIConfig_WhenIsActiveAPI_ShouldClearActiveSetting(t *tes
 	assert.Empty(t, config.Settings.ActiveAPI)
 }
 
+func TestManager_RemoveAPIConfig_WhenReferenced_ShouldDropReferences(t *testing.T) {
+	// Arrange
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "remove-fallback-test.toml")
+	manager := NewManager(configPath)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, manager.AddAPIConfig(&APIConfig{ID: id, Name: id, URL: "https://test.com"}))
+	}
+	cfg, err := manager.LoadConfig()
+	require.NoError(t, err)
+	cfg.Settings.Fallback = []string{"b", "c"}
+	cfg.Routes = []RouteConfig{{Path: "/v1/chat", API: "b"}, {Path: "/v1/messages", API: "a"}}
+	cfg.ModelRoutes = []ModelRouteConfig{{Model: "claude-*", API: "b"}}
+	require.NoError(t, manager.SaveConfig(cfg))
+
+	// Act
+	err = manager.RemoveAPIConfig("b")
+
+	// Assert
+	require.NoError(t, err)
+	config, err := manager.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, config.Settings.Fallback)
+	assert.Equal(t, []RouteConfig{{Path: "/v1/messages", API: "a"}}, config.Routes)
+	assert.Empty(t, config.ModelRoutes)
+}
+
 func TestManager_LoadConfig_WithInvalidTOMLFile_ShouldReturnError(t *testing.T) {
 	// Arrange
 	tempDir := t.TempDir()
@@ -77,6 +77,11 @@ func (pm *PathManager) StateFile() string {
 	return filepath.Join(pm.appDir, "state.json")
 }
 
+// TLSDir returns the directory of the generated local CA and certificates
+func (pm *PathManager) TLSDir() string {
+	return filepath.Join(pm.appDir, "tls")
+}
+
 // EnsureDirs creates all necessary directories
 func (pm *PathManager) EnsureDirs() error {
 	dirs := []string{
@@ -77,6 +77,7 @@ func TestPathManager_AllPaths(t *testing.T) {
 	assert.NotEmpty(t, pm.LogFile())
 	assert.NotEmpty(t, pm.PIDFile())
 	assert.NotEmpty(t, pm.StateFile())
+	assert.NotEmpty(t, pm.TLSDir())
 
 	// Test all paths are absolute
 	assert.True(t, filepath.IsAbs(pm.AppDir()))
@@ -86,6 +87,7 @@ func TestPathManager_AllPaths(t *testing.T) {
 	assert.True(t, filepath.IsAbs(pm.LogFile()))
 	assert.True(t, filepath.IsAbs(pm.PIDFile()))
 	assert.True(t, filepath.IsAbs(pm.StateFile()))
+	assert.True(t, filepath.IsAbs(pm.TLSDir()))
 }
 
 func TestGetDefaultPathManager(t *testing.T) {
@@ -1,17 +1,78 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
 // Config represents the main configuration structure
 type Config struct {
 	Server   ServerConfig `toml:"server"`
 	APIs     []APIConfig  `toml:"apis"`
 	Settings Settings     `toml:"settings"`
+
+	// Routes send requests to specific APIs by path. Requests matching no
+	// route go to the active API.
+	Routes []RouteConfig `toml:"routes,omitempty"`
+
+	// ModelRoutes send requests to specific APIs by the model they ask
+	// for. They take precedence over Routes.
+	ModelRoutes []ModelRouteConfig `toml:"model_routes,omitempty"`
 }
 
+// DefaultHost is the address the proxy binds to when none is configured,
+// so that only agents on the same machine can use its API keys
+const DefaultHost = "127.0.0.1"
+
 // ServerConfig represents the server configuration
 type ServerConfig struct {
+	// Host is the address to bind to, e.g. "127.0.0.1", "::1", "0.0.0.0"
+	// for every IPv4 interface or "::" for every interface of both stacks
+	Host     string `toml:"host"`
 	Port     int    `toml:"port"`
 	LogLevel string `toml:"log_level"`
 	Daemon   bool   `toml:"daemon"`
+
+	// Listen lists the addresses to accept clients on, as "tcp://host:port"
+	// or "unix:///path/to/octopus.sock". Empty listens on Host and Port.
+	Listen []string `toml:"listen,omitempty"`
+
+	// ClientTokens are the access tokens agents must present, as their API
+	// key, to use the proxy. Without any, every client is let through.
+	ClientTokens []ClientTokenConfig `toml:"client_tokens,omitempty"`
+
+	// TLS serves the proxy over HTTPS
+	TLS TLSConfig `toml:"tls,omitempty"`
+}
+
+// TLSConfig configures HTTPS on the proxy listener
+type TLSConfig struct {
+	Enabled bool `toml:"enabled"`
+	// CertFile and KeyFile are a PEM certificate chain and its key. Without
+	// them a local CA and a localhost certificate are generated. Either way
+	// the files are reloaded when they change.
+	CertFile string `toml:"cert_file,omitempty"`
+	KeyFile  string `toml:"key_file,omitempty"`
+}
+
+// Scheme returns the URL scheme clients use to reach the proxy
+func (s ServerConfig) Scheme() string {
+	if s.TLS.Enabled {
+		return "https"
+	}
+	return "http"
+}
+
+// ClientTokenConfig is an access token of the proxy itself
+type ClientTokenConfig struct {
+	// Token is the secret a client sends in place of an API key
+	Token string `toml:"token"`
+	// Label names the client in logs and stats, e.g. "laptop" or "ci"
+	Label string `toml:"label,omitempty"`
 }
 
 // APIConfig represents an API configuration
@@ -23,6 +84,232 @@ type APIConfig struct {
 	IsActive   bool   `toml:"is_active"`
 	Timeout    int    `toml:"timeout"`
 	RetryCount int    `toml:"retry_count"`
+
+	// AuthStyle controls how APIKey is sent upstream: "bearer" (default),
+	// "anthropic", "google", "google-query", "azure" or "none"
+	AuthStyle string `toml:"auth_style,omitempty"`
+
+	// Provider selects vendor-specific behavior such as the default auth
+	// style, health endpoint and usage reporting: "anthropic", "openai",
+	// "gemini", "azure-openai", "bedrock", "vertex" or "generic". Empty
+	// infers it from Protocol.
+	Provider string `toml:"provider,omitempty"`
+
+	// Protocol is the API dialect the upstream speaks, "anthropic",
+	// "openai" or "gemini". Requests in another dialect are translated on
+	// the way. Empty uses the provider's protocol, and generic APIs get
+	// requests as they are.
+	Protocol string `toml:"protocol,omitempty"`
+
+	// Per-phase timeouts in seconds. Zero falls back to Timeout, and a
+	// response body is never cut off while it keeps producing data.
+	ConnectTimeout   int `toml:"connect_timeout,omitempty"`
+	FirstByteTimeout int `toml:"first_byte_timeout,omitempty"`
+	IdleTimeout      int `toml:"idle_timeout,omitempty"`
+
+	// ModelMap renames models on the way out, keyed by the name the agent
+	// asks for, e.g. "claude-sonnet-4-20250514" = "anthropic/claude-sonnet-4".
+	// With RewriteResponseModel, responses report the agent's name again.
+	ModelMap             map[string]string `toml:"model_map,omitempty"`
+	RewriteResponseModel bool              `toml:"rewrite_response_model,omitempty"`
+
+	// Region is the cloud region of the bedrock and vertex providers.
+	// Without a URL the regional endpoint is used.
+	Region string `toml:"region,omitempty"`
+
+	// AWS credentials of the bedrock provider. Without static keys they
+	// come from AWSProfile in the shared credentials file, then from the
+	// AWS_* environment variables, then from the default profile.
+	AWSAccessKeyID     string `toml:"aws_access_key_id,omitempty"`
+	AWSSecretAccessKey string `toml:"aws_secret_access_key,omitempty"`
+	AWSSessionToken    string `toml:"aws_session_token,omitempty"`
+	AWSProfile         string `toml:"aws_profile,omitempty"`
+
+	// Google Cloud settings of the vertex provider. CredentialsFile is a
+	// service-account JSON key, defaulting to GOOGLE_APPLICATION_CREDENTIALS,
+	// and ProjectID defaults to the key's project. TokenURL overrides the
+	// OAuth endpoint access tokens are minted at.
+	ProjectID       string `toml:"project_id,omitempty"`
+	CredentialsFile string `toml:"credentials_file,omitempty"`
+	TokenURL        string `toml:"token_url,omitempty"`
+
+	// Azure OpenAI settings of the azure-openai provider. Deployments maps
+	// model names, after any ModelMap renaming, to deployment names, which
+	// default to the model name. APIVersion is sent as the api-version
+	// query parameter.
+	Deployments map[string]string `toml:"deployments,omitempty"`
+	APIVersion  string            `toml:"api_version,omitempty"`
+
+	// Rate limits of the API; zero leaves a limit off. Requests beyond
+	// them wait in a queue of QueueSize requests (default 100) for up to
+	// QueueTimeout seconds (default 60) before failing over or failing
+	// with 429. Input tokens are estimated from the request body and
+	// corrected once the upstream reports usage.
+	RequestsPerMinute    int `toml:"requests_per_minute,omitempty"`
+	InputTokensPerMinute int `toml:"input_tokens_per_minute,omitempty"`
+	MaxConcurrent        int `toml:"max_concurrent,omitempty"`
+	QueueSize            int `toml:"queue_size,omitempty"`
+	QueueTimeout         int `toml:"queue_timeout,omitempty"`
+}
+
+// BindHost returns the configured host without IPv6 brackets, or
+// DefaultHost when none is set
+func (s ServerConfig) BindHost() string {
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s.Host), "["), "]")
+	if host == "" {
+		return DefaultHost
+	}
+	return host
+}
+
+// ListenAddress returns the host:port of Host and Port
+func (s ServerConfig) ListenAddress() string {
+	return net.JoinHostPort(s.BindHost(), strconv.Itoa(s.Port))
+}
+
+// Listener is an address the proxy accepts clients on
+type Listener struct {
+	// Network is "tcp" or "unix"
+	Network string
+	// Address is a host:port for tcp and a socket path for unix
+	Address string
+}
+
+// String formats l the way it is written in the listen list
+func (l Listener) String() string {
+	return l.Network + "://" + l.Address
+}
+
+// IsLoopback reports whether only this machine can connect to l
+func (l Listener) IsLoopback() bool {
+	if l.Network == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(l.Address)
+	return err == nil && isLoopbackHost(host)
+}
+
+// ParseListener parses a "tcp://host:port" or "unix://path" listen entry.
+// A tcp entry without a host binds to DefaultHost, and a socket path may
+// start with "~/" for the home directory.
+func ParseListener(entry string) (Listener, error) {
+	network, address, ok := strings.Cut(strings.TrimSpace(entry), "://")
+	if !ok || address == "" {
+		return Listener{}, fmt.Errorf("invalid listen address %q, expected tcp://host:port or unix://path", entry)
+	}
+
+	switch network {
+	case "tcp":
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return Listener{}, fmt.Errorf("invalid listen address %q: %w", entry, err)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return Listener{}, fmt.Errorf("invalid port in listen address %q", entry)
+		}
+		if host == "" {
+			host = DefaultHost
+		}
+		return Listener{Network: network, Address: net.JoinHostPort(host, port)}, nil
+	case "unix":
+		if rest, ok := strings.CutPrefix(address, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return Listener{}, fmt.Errorf("failed to expand %q: %w", entry, err)
+			}
+			address = filepath.Join(home, rest)
+		}
+		return Listener{Network: network, Address: filepath.Clean(address)}, nil
+	default:
+		return Listener{}, fmt.Errorf("unsupported network %q in listen address %q, expected tcp or unix", network, entry)
+	}
+}
+
+// Listeners returns the addresses the proxy listens on: the Listen entries,
+// or Host and Port when there are none
+func (s ServerConfig) Listeners() ([]Listener, error) {
+	if len(s.Listen) == 0 {
+		return []Listener{{Network: "tcp", Address: s.ListenAddress()}}, nil
+	}
+
+	listeners := make([]Listener, 0, len(s.Listen))
+	for _, entry := range s.Listen {
+		listener, err := ParseListener(entry)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// ClientAuthEnabled reports whether clients must present an access token
+func (s ServerConfig) ClientAuthEnabled() bool {
+	return len(s.ClientTokens) > 0
+}
+
+// ValidateClientTokens rejects client_tokens entries without a token, which
+// no client could ever present
+func (s ServerConfig) ValidateClientTokens() error {
+	for i, token := range s.ClientTokens {
+		if strings.TrimSpace(token.Token) == "" {
+			if token.Label != "" {
+				return fmt.Errorf("client token '%s' has an empty token", token.Label)
+			}
+			return fmt.Errorf("client token %d has an empty token", i+1)
+		}
+	}
+	return nil
+}
+
+// IsLoopback reports whether the proxy is only reachable from this
+// machine, which an invalid listen list is not assumed to be
+func (s ServerConfig) IsLoopback() bool {
+	listeners, err := s.Listeners()
+	if err != nil {
+		return false
+	}
+	for _, listener := range listeners {
+		if !listener.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// RouteConfig maps a request path prefix to an API
+type RouteConfig struct {
+	// Path is matched against the start of the request path at a segment
+	// boundary, e.g. "/v1/messages" or "/v1beta/models/*". The longest
+	// matching path wins.
+	Path string `toml:"path" json:"path"`
+	// API is the ID of the API serving matching requests
+	API string `toml:"api" json:"api"`
+	// StripPrefix removes the matched path before forwarding
+	StripPrefix bool `toml:"strip_prefix,omitempty" json:"strip_prefix,omitempty"`
+}
+
+// ModelRouteConfig maps a model name pattern to an API
+type ModelRouteConfig struct {
+	// Model is a glob matched against the "model" field of the request
+	// body, e.g. "claude-opus-*". Rules are tried in order.
+	Model string `toml:"model" json:"model"`
+	// API is the ID of the API serving matching requests
+	API string `toml:"api" json:"api"`
+}
+
+// GroupMember is an API taking part in the active group
+type GroupMember struct {
+	ID     string `toml:"id" json:"id"`
+	Weight int    `toml:"weight,omitempty" json:"weight,omitempty"`
 }
 
 // Settings represents global settings
@@ -30,6 +317,53 @@ type Settings struct {
 	ActiveAPI    string `toml:"active_api"`
 	LogFile      string `toml:"log_file"`
 	ConfigBackup bool   `toml:"config_backup"`
+
+	// AgentAPIs gives detected agents their own active API, keyed by agent
+	// name ("claude-code", "codex", "gemini", "codebuddy" or "generic").
+	// Agents without an entry use ActiveAPI.
+	AgentAPIs map[string]string `toml:"agent_apis,omitempty"`
+
+	// RetryBodyLimitMB caps the request body size buffered for retries.
+	// Larger requests are forwarded once without retries. Defaults to 32.
+	RetryBodyLimitMB int `toml:"retry_body_limit_mb,omitempty"`
+
+	// Fallback lists API IDs tried in order when the active API fails with
+	// a 5xx, 429 or connection error. A failed API is skipped for
+	// FailoverCooldown seconds (default 60) before it is tried again.
+	Fallback         []string `toml:"fallback,omitempty"`
+	FailoverCooldown int      `toml:"failover_cooldown,omitempty"`
+
+	// ForwardedHeaders adds X-Forwarded-For, X-Forwarded-Host,
+	// X-Forwarded-Proto and Via headers to upstream requests. Off by default
+	// so client addresses are not disclosed to third-party APIs.
+	ForwardedHeaders bool `toml:"forwarded_headers,omitempty"`
+
+	// Connection pool limits of the upstream transports, kept per API.
+	// MaxIdleConns (default 100) and MaxIdleConnsPerHost (default 16) bound
+	// the idle connections kept open for reuse, and IdleConnTimeout closes
+	// them after that many seconds unused (default 90).
+	MaxIdleConns        int `toml:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int `toml:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     int `toml:"idle_conn_timeout,omitempty"`
+
+	// ActiveGroup spreads traffic across several APIs instead of the single
+	// ActiveAPI. Each request goes to one member, picked by BalanceStrategy:
+	// "round-robin" (default), "weighted-random", "least-in-flight" or
+	// "lowest-latency". Weights default to 1.
+	ActiveGroup     []GroupMember `toml:"active_group,omitempty"`
+	BalanceStrategy string        `toml:"balance_strategy,omitempty"`
+
+	// Circuit breaker thresholds. A circuit opens after
+	// BreakerFailureThreshold consecutive failures (default 5), or when at
+	// least BreakerMinRequests requests (default 10) within
+	// BreakerWindow seconds (default 60) fail at BreakerErrorRate or more
+	// (default 0.5). An open circuit lets a single probe through after
+	// BreakerOpenTimeout seconds (default 30).
+	BreakerFailureThreshold int     `toml:"breaker_failure_threshold,omitempty"`
+	BreakerErrorRate        float64 `toml:"breaker_error_rate,omitempty"`
+	BreakerMinRequests      int     `toml:"breaker_min_requests,omitempty"`
+	BreakerWindow           int     `toml:"breaker_window,omitempty"`
+	BreakerOpenTimeout      int     `toml:"breaker_open_timeout,omitempty"`
 }
 
 // DefaultConfig returns a default configuration
@@ -37,6 +371,7 @@ func DefaultConfig() *Config {
 	pm := GetDefaultPathManager()
 	return &Config{
 		Server: ServerConfig{
+			Host:     DefaultHost,
 			Port:     8080,
 			LogLevel: "info",
 			Daemon:   true,
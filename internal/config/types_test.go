@@ -1,9 +1,12 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAPIConfig_StructureTags_ShouldHaveCorrectTOMLTags(t *testing.T) {
@@ -34,17 +37,48 @@ func TestAPIConfig_StructureTags_ShouldHaveCorrectTOMLTags(t *testing.T) {
 func TestServerConfig_StructureTags_ShouldHaveCorrectTOMLTags(t *testing.T) {
 	// Arrange
 	server := ServerConfig{
+		Host:     "127.0.0.1",
 		Port:     8080,
 		LogLevel: "debug",
 		Daemon:   false,
 	}
 
 	// Act & Assert
+	assert.Equal(t, "127.0.0.1", server.Host)
 	assert.Equal(t, 8080, server.Port)
 	assert.Equal(t, "debug", server.LogLevel)
 	assert.False(t, server.Daemon)
 }
 
+func TestServerConfig_Address_ShouldDefaultToLoopbackAndDetectExposure(t *testing.T) {
+	tests := []struct {
+		host     string
+		address  string
+		loopback bool
+	}{
+		{"", "127.0.0.1:8080", true},
+		{"localhost", "localhost:8080", true},
+		{"::1", "[::1]:8080", true},
+		{"[::1]", "[::1]:8080", true},
+		{"127.0.0.2", "127.0.0.2:8080", true},
+		{"0.0.0.0", "0.0.0.0:8080", false},
+		{"::", "[::]:8080", false},
+		{"192.168.1.20", "192.168.1.20:8080", false},
+		{"proxy.internal", "proxy.internal:8080", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			// Arrange
+			server := ServerConfig{Host: tt.host, Port: 8080}
+
+			// Act & Assert
+			assert.Equal(t, tt.address, server.ListenAddress())
+			assert.Equal(t, tt.loopback, server.IsLoopback())
+		})
+	}
+}
+
 func TestSettings_StructureTags_ShouldHaveCorrectTOMLTags(t *testing.T) {
 	// Arrange
 	settings := Settings{
@@ -116,6 +150,7 @@ func TestDefaultConfig_Values_ShouldMatchExpectedDefaults(t *testing.T) {
 
 	// Assert - verify all default values are as expected
 	// Server defaults
+	assert.Equal(t, "127.0.0.1", config.Server.Host)
 	assert.Equal(t, 8080, config.Server.Port)
 	assert.Equal(t, "info", config.Server.LogLevel)
 	assert.True(t, config.Server.Daemon)
@@ -170,3 +205,78 @@ func TestConfig_TOMLFieldNames_ShouldBeSnakeCase(t *testing.T) {
 	// - APIs.RetryCount should serialize to "retry_count"
 	// Note: Server.PIDFile was removed - now managed internally
 }
+
+func TestParseListener_ShouldAcceptTCPAndUnixEntries(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	tests := []struct {
+		entry    string
+		expected Listener
+		loopback bool
+		wantErr  bool
+	}{
+		{entry: "tcp://127.0.0.1:8080", expected: Listener{"tcp", "127.0.0.1:8080"}, loopback: true},
+		{entry: "tcp://:8080", expected: Listener{"tcp", "127.0.0.1:8080"}, loopback: true},
+		{entry: "tcp://[::]:8080", expected: Listener{"tcp", "[::]:8080"}},
+		{entry: "unix:///run/octopus.sock", expected: Listener{"unix", filepath.Clean("/run/octopus.sock")}, loopback: true},
+		{entry: "unix://~/.octopus/octopus.sock", expected: Listener{"unix", filepath.Join(home, ".octopus", "octopus.sock")}, loopback: true},
+		{entry: "127.0.0.1:8080", wantErr: true},
+		{entry: "tcp://127.0.0.1", wantErr: true},
+		{entry: "tcp://127.0.0.1:http", wantErr: true},
+		{entry: "udp://127.0.0.1:8080", wantErr: true},
+		{entry: "unix://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			// Act
+			listener, err := ParseListener(tt.entry)
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, listener)
+			assert.Equal(t, tt.loopback, listener.IsLoopback())
+		})
+	}
+}
+
+func TestServerConfig_Listeners_ShouldPreferListenOverHostAndPort(t *testing.T) {
+	// Arrange
+	server := ServerConfig{
+		Host:   "0.0.0.0",
+		Port:   8080,
+		Listen: []string{"tcp://127.0.0.1:9090", "unix:///tmp/octopus.sock"},
+	}
+
+	// Act
+	listeners, err := server.Listeners()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []Listener{{"tcp", "127.0.0.1:9090"}, {"unix", filepath.Clean("/tmp/octopus.sock")}}, listeners)
+	assert.True(t, server.IsLoopback())
+}
+
+func TestServerConfig_ValidateClientTokens_WithEmptyToken_ShouldReturnError(t *testing.T) {
+	// Arrange
+	valid := ServerConfig{ClientTokens: []ClientTokenConfig{{Token: "secret", Label: "laptop"}}}
+	unlabeled := ServerConfig{ClientTokens: []ClientTokenConfig{{Token: "secret"}, {Token: " "}}}
+	labeled := ServerConfig{ClientTokens: []ClientTokenConfig{{Label: "ci"}}}
+
+	// Act
+	validErr := valid.ValidateClientTokens()
+	unlabeledErr := unlabeled.ValidateClientTokens()
+	labeledErr := labeled.ValidateClientTokens()
+
+	// Assert
+	assert.NoError(t, validErr)
+	require.Error(t, unlabeledErr)
+	assert.Contains(t, unlabeledErr.Error(), "client token 2 has an empty token")
+	require.Error(t, labeledErr)
+	assert.Contains(t, labeledErr.Error(), "client token 'ci'")
+}
@@ -6,8 +6,11 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
+
+	"octopus-cli/internal/config"
 )
 
 // ProcessStatus represents the status of the daemon process
@@ -20,18 +23,22 @@ type ProcessStatus struct {
 
 // Manager handles process lifecycle management
 type Manager struct {
-	pidFile string
-	name    string
+	pidFile   string
+	adminFile string
+	name      string
 }
 
 // NewManager creates a new process manager
 func NewManager(name string) *Manager {
 	// Use cross-platform temp directory for PID file
 	pidFile := filepath.Join(os.TempDir(), "octopus.pid")
+	// The admin token must not live in a directory other users can write to
+	adminFile := filepath.Join(config.NewPathManager().AppDir(), "octopus.admin")
 
 	return &Manager{
-		pidFile: pidFile,
-		name:    name,
+		pidFile:   pidFile,
+		adminFile: adminFile,
+		name:      name,
 	}
 }
 
@@ -79,6 +86,7 @@ func (m *Manager) StopDaemon() error {
 
 	// Wait for graceful shutdown, then cleanup
 	time.Sleep(100 * time.Millisecond)
+	m.CleanupAdminFile()
 	return m.CleanupPIDFile()
 }
 
@@ -140,6 +148,48 @@ func (m *Manager) CleanupPIDFile() error {
 	return os.Remove(m.pidFile)
 }
 
+// WriteAdminInfo records the daemon's admin endpoint address and token. Any
+// existing file is replaced by a new one so that its owner and mode cannot
+// be inherited.
+func (m *Manager) WriteAdminInfo(addr, token string) error {
+	if err := os.MkdirAll(filepath.Dir(m.adminFile), 0700); err != nil {
+		return fmt.Errorf("failed to create admin file directory: %w", err)
+	}
+	if err := os.Remove(m.adminFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale admin file: %w", err)
+	}
+
+	file, err := os.OpenFile(m.adminFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create admin file: %w", err)
+	}
+	if _, err := file.WriteString(addr + "\n" + token); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write admin file: %w", err)
+	}
+	return file.Close()
+}
+
+// ReadAdminInfo returns the admin endpoint address and token of the daemon
+func (m *Manager) ReadAdminInfo() (string, string, error) {
+	data, err := os.ReadFile(m.adminFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid admin file: %s", m.adminFile)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// CleanupAdminFile removes the admin endpoint file
+func (m *Manager) CleanupAdminFile() error {
+	return os.Remove(m.adminFile)
+}
+
 // GetPIDFilePath returns the PID file path being used
 func (m *Manager) GetPIDFilePath() string {
 	return m.pidFile
@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
 )
 
 func TestNewManager_WithValidParameters_ShouldCreateManager(t *testing.T) {
@@ -510,3 +511,57 @@ func TestManager_ProcessStatus_Fields_ShouldHaveCorrectTypes(t *testing.T) {
 	// Cleanup
 	manager.CleanupPIDFile()
 }
+
+func TestManager_WriteAdminInfo_ShouldRoundTripAddressAndToken(t *testing.T) {
+	// Arrange
+	manager := NewManager("test")
+	manager.adminFile = filepath.Join(t.TempDir(), "octopus.admin")
+
+	// Act
+	require.NoError(t, manager.WriteAdminInfo("127.0.0.1:4567", "secret"))
+	addr, token, err := manager.ReadAdminInfo()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:4567", addr)
+	assert.Equal(t, "secret", token)
+
+	info, err := os.Stat(manager.adminFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Cleanup
+	require.NoError(t, manager.CleanupAdminFile())
+	_, _, err = manager.ReadAdminInfo()
+	assert.Error(t, err)
+}
+
+func TestManager_WriteAdminInfo_WithExistingFile_ShouldReplaceItWithPrivateFile(t *testing.T) {
+	// Arrange - a readable file planted before the daemon starts
+	manager := NewManager("test")
+	manager.adminFile = filepath.Join(t.TempDir(), "octopus.admin")
+	require.NoError(t, os.WriteFile(manager.adminFile, []byte("127.0.0.1:1\nplanted"), 0644))
+	require.NoError(t, os.Chmod(manager.adminFile, 0644))
+
+	// Act
+	err := manager.WriteAdminInfo("127.0.0.1:4567", "secret")
+
+	// Assert
+	require.NoError(t, err)
+	addr, token, err := manager.ReadAdminInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:4567", addr)
+	assert.Equal(t, "secret", token)
+
+	info, err := os.Stat(manager.adminFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestNewManager_ShouldKeepAdminFileInAppDir(t *testing.T) {
+	// Act
+	manager := NewManager("test")
+
+	// Assert
+	assert.Equal(t, filepath.Join(config.NewPathManager().AppDir(), "octopus.admin"), manager.adminFile)
+}
@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// AdminTokenHeader is the header carrying the admin endpoint token
+const AdminTokenHeader = "X-Octopus-Admin-Token"
+
+// AdminStatus represents the live state reported by the admin endpoint
+type AdminStatus struct {
+	ActiveAPI         string                   `json:"active_api"`
+	RequestCount      int64                    `json:"request_count"`
+	ErrorCount        int64                    `json:"error_count"`
+	UnauthorizedCount int64                    `json:"unauthorized_count,omitempty"`
+	UptimeSeconds     float64                  `json:"uptime_seconds"`
+	Breakers          []BreakerStatus          `json:"breakers,omitempty"`
+	Upstreams         map[string]UpstreamStats `json:"upstreams,omitempty"`
+	Clients           map[string]int64         `json:"clients,omitempty"`
+}
+
+// adminSwitchRequest is the body of a switch request
+type adminSwitchRequest struct {
+	ID    string `json:"id"`
+	Agent string `json:"agent,omitempty"`
+}
+
+// adminError is the body of an admin error response
+type adminError struct {
+	Error string `json:"error"`
+}
+
+// adminServer exposes a loopback-only control channel for the running proxy
+type adminServer struct {
+	proxy    *Server
+	token    string
+	listener net.Listener
+	server   *http.Server
+}
+
+// newAdminServer starts the admin endpoint on a random loopback port
+func newAdminServer(s *Server) (*adminServer, error) {
+	token, err := generateAdminToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate admin token: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on loopback: %w", err)
+	}
+
+	a := &adminServer{
+		proxy:    s,
+		token:    token,
+		listener: listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", a.handleStatus)
+	mux.HandleFunc("/switch", a.handleSwitch)
+	mux.HandleFunc("/apis", a.handleAPIs)
+	mux.HandleFunc("/apis/", a.handleAPI)
+
+	a.server = &http.Server{
+		Handler:           a.authorize(mux),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := a.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			if s.logger != nil {
+				s.logger.Error("Admin endpoint error: %v", err)
+			}
+		}
+	}()
+
+	return a, nil
+}
+
+// Addr returns the address the admin endpoint is listening on
+func (a *adminServer) Addr() string {
+	return a.listener.Addr().String()
+}
+
+// Close stops the admin endpoint
+func (a *adminServer) Close() error {
+	return a.server.Close()
+}
+
+// authorize rejects requests that do not carry the admin token
+func (a *adminServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(a.token)) != 1 {
+			writeAdminError(w, http.StatusUnauthorized, fmt.Errorf("invalid admin token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStatus reports the active API and request counters
+func (a *adminServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	stats := a.proxy.GetStats()
+	writeAdminJSON(w, http.StatusOK, AdminStatus{
+		ActiveAPI:         a.proxy.configManager.GetActiveAPIID(),
+		RequestCount:      stats.RequestCount,
+		ErrorCount:        stats.ErrorCount,
+		UnauthorizedCount: stats.UnauthorizedCount,
+		UptimeSeconds:     stats.Uptime.Seconds(),
+		Breakers:          a.proxy.BreakerStatuses(),
+		Upstreams:         stats.Upstreams,
+		Clients:           stats.Clients,
+	})
+}
+
+// handleSwitch switches the active API, or the API of a single agent, for
+// subsequent requests
+func (a *adminServer) handleSwitch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	var req adminSwitchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	if req.Agent != "" {
+		if !IsKnownAgent(req.Agent) {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("unknown agent %q", req.Agent))
+			return
+		}
+		if err := a.proxy.configManager.SwitchAgentAPI(req.Agent, req.ID); err != nil {
+			writeAdminError(w, http.StatusNotFound, err)
+			return
+		}
+		if a.proxy.logger != nil {
+			a.proxy.logger.Info("API of agent '%s' switched to '%s' via admin endpoint", req.Agent, req.ID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	previous := a.proxy.configManager.GetActiveAPIID()
+	if err := a.proxy.configManager.SwitchAPI(req.ID); err != nil {
+		writeAdminError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if a.proxy.logger != nil {
+		a.proxy.logger.Info("Active API switched from '%s' to '%s' via admin endpoint", previous, req.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAPIs adds or updates an API configuration
+func (a *adminServer) handleAPIs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	var api config.APIConfig
+	if err := json.NewDecoder(r.Body).Decode(&api); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if api.ID == "" {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("API ID is required"))
+		return
+	}
+
+	action := "added"
+	var err error
+	if r.Method == http.MethodPut {
+		action = "updated"
+		err = a.proxy.UpdateConfig(&api)
+	} else {
+		err = a.proxy.configManager.AddAPI(api)
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, err)
+		return
+	}
+
+	if a.proxy.logger != nil {
+		a.proxy.logger.Info("API '%s' %s via admin endpoint", api.ID, action)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAPI removes a single API configuration
+func (a *adminServer) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/apis/")
+	if err := a.proxy.configManager.RemoveAPI(id); err != nil {
+		writeAdminError(w, http.StatusNotFound, err)
+		return
+	}
+	a.proxy.forgetAPI(id)
+
+	if a.proxy.logger != nil {
+		a.proxy.logger.Info("API '%s' removed via admin endpoint", id)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAdminJSON writes a JSON response
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAdminError writes a JSON error response
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, adminError{Error: err.Error()})
+}
+
+// generateAdminToken returns a random hex token
+func generateAdminToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
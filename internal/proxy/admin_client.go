@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// AdminClient talks to the admin endpoint of a running proxy server
+type AdminClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewAdminClient creates a new admin client for the given loopback address
+func NewAdminClient(addr, token string) *AdminClient {
+	return &AdminClient{
+		baseURL: "http://" + addr,
+		token:   token,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				Proxy: nil, // Never route loopback control traffic through a proxy
+			},
+		},
+	}
+}
+
+// Status returns the live state of the proxy server
+func (c *AdminClient) Status() (*AdminStatus, error) {
+	resp, err := c.do(http.MethodGet, "/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status AdminStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode admin status: %w", err)
+	}
+	return &status, nil
+}
+
+// SwitchAPI switches the active API of the running server
+func (c *AdminClient) SwitchAPI(apiID string) error {
+	return c.call(http.MethodPost, "/switch", adminSwitchRequest{ID: apiID})
+}
+
+// SwitchAgentAPI switches the API of a single agent on the running server
+func (c *AdminClient) SwitchAgentAPI(agent, apiID string) error {
+	return c.call(http.MethodPost, "/switch", adminSwitchRequest{ID: apiID, Agent: agent})
+}
+
+// AddAPI adds an API configuration to the running server
+func (c *AdminClient) AddAPI(api config.APIConfig) error {
+	return c.call(http.MethodPost, "/apis", api)
+}
+
+// UpdateAPI replaces or adds an API configuration on the running server
+func (c *AdminClient) UpdateAPI(api config.APIConfig) error {
+	return c.call(http.MethodPut, "/apis", api)
+}
+
+// RemoveAPI removes an API configuration from the running server
+func (c *AdminClient) RemoveAPI(apiID string) error {
+	return c.call(http.MethodDelete, "/apis/"+url.PathEscape(apiID), nil)
+}
+
+// call performs a request that carries no response body
+func (c *AdminClient) call(method, path string, body interface{}) error {
+	resp, err := c.do(method, path, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do performs an authenticated request and converts error responses
+func (c *AdminClient) do(method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode admin request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin request: %w", err)
+	}
+	req.Header.Set(AdminTokenHeader, c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("admin endpoint unreachable: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr adminError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return nil, fmt.Errorf("admin request failed with status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("admin request failed: %s", apiErr.Error)
+	}
+
+	return resp, nil
+}
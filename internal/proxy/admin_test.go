@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// newNamedTarget creates a target server that responds with its own name
+func newNamedTarget(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(name))
+	}))
+}
+
+// getProxyBody performs a GET through the proxy and returns the response body
+func getProxyBody(t *testing.T, server *Server) string {
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/test", server.GetPort()))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestServer_Start_ShouldExposeLoopbackAdminEndpoint(t *testing.T) {
+	// Arrange
+	server := NewServer(&config.Config{Server: config.ServerConfig{Port: 0}})
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	addr := server.AdminAddr()
+
+	// Assert
+	assert.Contains(t, addr, "127.0.0.1:")
+	assert.NotEmpty(t, server.AdminToken())
+}
+
+func TestAdminClient_SwitchAPI_ShouldApplyToNextRequestWithoutRestart(t *testing.T) {
+	// Arrange
+	target1 := newNamedTarget("target-1")
+	defer target1.Close()
+	target2 := newNamedTarget("target-2")
+	defer target2.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "api1", URL: target1.URL},
+			{ID: "api2", URL: target2.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "api1"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	client := NewAdminClient(server.AdminAddr(), server.AdminToken())
+	require.Equal(t, "target-1", getProxyBody(t, server))
+
+	// Act
+	err := client.SwitchAPI("api2")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, server.IsRunning())
+	assert.Equal(t, "target-2", getProxyBody(t, server))
+
+	status, err := client.Status()
+	require.NoError(t, err)
+	assert.Equal(t, "api2", status.ActiveAPI)
+	assert.Equal(t, int64(2), status.RequestCount)
+}
+
+func TestAdminClient_SwitchAPI_WithUnknownAPI_ShouldReturnError(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "api1", URL: "https://api1.com"}},
+		Settings: config.Settings{ActiveAPI: "api1"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	client := NewAdminClient(server.AdminAddr(), server.AdminToken())
+
+	// Act
+	err := client.SwitchAPI("missing")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "API not found")
+}
+
+func TestAdminClient_AddAndRemoveAPI_ShouldUpdateRunningServer(t *testing.T) {
+	// Arrange
+	target := newNamedTarget("added")
+	defer target.Close()
+
+	server := NewServer(&config.Config{Server: config.ServerConfig{Port: 0}})
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	client := NewAdminClient(server.AdminAddr(), server.AdminToken())
+
+	// Act & Assert - add then switch
+	require.NoError(t, client.AddAPI(config.APIConfig{ID: "new", URL: target.URL}))
+	require.NoError(t, client.SwitchAPI("new"))
+	assert.Equal(t, "added", getProxyBody(t, server))
+
+	// Act & Assert - duplicate add is rejected
+	err := client.AddAPI(config.APIConfig{ID: "new", URL: target.URL})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	// Act & Assert - remove clears the active API
+	require.NoError(t, client.RemoveAPI("new"))
+	assert.Contains(t, getProxyBody(t, server), "no active API")
+}
+
+func TestAdminClient_RemoveAPI_ShouldDropItsRateLimiterAndTransport(t *testing.T) {
+	// Arrange
+	target := newNamedTarget("limited")
+	defer target.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "limited", URL: target.URL, RequestsPerMinute: 10}},
+		Settings: config.Settings{ActiveAPI: "limited"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+	assert.Equal(t, "limited", getProxyBody(t, server))
+
+	client := NewAdminClient(server.AdminAddr(), server.AdminToken())
+
+	// Act
+	err := client.RemoveAPI("limited")
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotContains(t, server.limiters.queued(), "limited")
+	assert.NotContains(t, server.transports.transports, "limited")
+}
+
+func TestAdminClient_RemoveAndAddAPIAgain_ShouldStartWithFreshCircuit(t *testing.T) {
+	// Arrange - a failing API whose circuit opens after one failure
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := newNamedTarget("healthy")
+	defer healthy.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "api", URL: failing.URL}},
+		Settings: config.Settings{ActiveAPI: "api", BreakerFailureThreshold: 1},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+	getProxyBody(t, server)
+	require.Equal(t, BreakerOpen, server.BreakerStatuses()[0].State)
+
+	client := NewAdminClient(server.AdminAddr(), server.AdminToken())
+
+	// Act
+	require.NoError(t, client.RemoveAPI("api"))
+	statusesAfterRemove := server.BreakerStatuses()
+	upstreamsAfterRemove := server.GetStats().Upstreams
+	require.NoError(t, client.AddAPI(config.APIConfig{ID: "api", URL: healthy.URL}))
+	require.NoError(t, client.SwitchAPI("api"))
+	body := getProxyBody(t, server)
+
+	// Assert
+	assert.Empty(t, statusesAfterRemove)
+	assert.NotContains(t, upstreamsAfterRemove, "api")
+	assert.Equal(t, "healthy", body)
+	assert.Equal(t, BreakerClosed, server.BreakerStatuses()[0].State)
+}
+
+func TestAdminClient_WithWrongToken_ShouldBeRejected(t *testing.T) {
+	// Arrange
+	server := NewServer(&config.Config{Server: config.ServerConfig{Port: 0}})
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	client := NewAdminClient(server.AdminAddr(), "wrong-token")
+
+	// Act
+	_, err := client.Status()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid admin token")
+}
+
+func TestServer_UpdateConfig_WithExistingAPI_ShouldReplaceIt(t *testing.T) {
+	// Arrange
+	target := newNamedTarget("updated")
+	defer target.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "api1", URL: "http://127.0.0.1:1"}},
+		Settings: config.Settings{ActiveAPI: "api1"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	err := server.UpdateConfig(&config.APIConfig{ID: "api1", URL: target.URL})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "updated", getProxyBody(t, server))
+}
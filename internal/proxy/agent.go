@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Agents recognised by detectAgent
+const (
+	AgentClaudeCode = "claude-code"
+	AgentCodex      = "codex"
+	AgentGemini     = "gemini"
+	AgentCodeBuddy  = "codebuddy"
+	AgentGeneric    = "generic"
+)
+
+// KnownAgents lists every agent name accepted in the agent_apis setting
+var KnownAgents = []string{AgentClaudeCode, AgentCodex, AgentGemini, AgentCodeBuddy, AgentGeneric}
+
+// IsKnownAgent reports whether name is one of KnownAgents
+func IsKnownAgent(name string) bool {
+	for _, agent := range KnownAgents {
+		if agent == name {
+			return true
+		}
+	}
+	return false
+}
+
+// agentSignature identifies an agent by headers it is known to send
+type agentSignature struct {
+	agent string
+	// header and the lowercase fragment its value must contain; an empty
+	// fragment only requires the header to be present
+	header   string
+	fragment string
+}
+
+// agentSignatures are checked in order; the first match wins
+var agentSignatures = []agentSignature{
+	{AgentClaudeCode, "User-Agent", "claude-cli"},
+	{AgentClaudeCode, "User-Agent", "claude-code"},
+	{AgentClaudeCode, "X-App", "cli"},
+	{AgentClaudeCode, "Anthropic-Beta", "claude-code"},
+	{AgentCodex, "Originator", "codex"},
+	{AgentCodex, "User-Agent", "codex"},
+	{AgentGemini, "User-Agent", "geminicli"},
+	{AgentGemini, "User-Agent", "gemini-cli"},
+	{AgentGemini, "X-Goog-Api-Client", "gemini-cli"},
+	{AgentCodeBuddy, "User-Agent", "codebuddy"},
+	{AgentCodeBuddy, "X-Codebuddy-Request", ""},
+}
+
+// detectAgent identifies the calling agent from its User-Agent and other
+// well-known headers, returning AgentGeneric when nothing matches
+func detectAgent(r *http.Request) string {
+	for _, sig := range agentSignatures {
+		values := r.Header.Values(sig.header)
+		if len(values) == 0 {
+			continue
+		}
+		if sig.fragment == "" {
+			return sig.agent
+		}
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), sig.fragment) {
+				return sig.agent
+			}
+		}
+	}
+	return AgentGeneric
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestDetectAgent_WithKnownHeaders_ShouldIdentifyAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{"claude code user agent", map[string]string{"User-Agent": "claude-cli/1.0.83 (external, cli)"}, AgentClaudeCode},
+		{"claude code app header", map[string]string{"User-Agent": "node", "X-App": "cli"}, AgentClaudeCode},
+		{"codex user agent", map[string]string{"User-Agent": "codex_cli_rs/0.20.0 (Mac OS 14.5.0; arm64)"}, AgentCodex},
+		{"codex originator", map[string]string{"Originator": "codex_cli_rs"}, AgentCodex},
+		{"gemini cli", map[string]string{"User-Agent": "GeminiCLI/0.1.18 (linux; x64)"}, AgentGemini},
+		{"codebuddy", map[string]string{"User-Agent": "CodeBuddy/2.3.1"}, AgentCodeBuddy},
+		{"curl", map[string]string{"User-Agent": "curl/8.4.0"}, AgentGeneric},
+		{"no headers", map[string]string{}, AgentGeneric},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("POST", "/v1/messages", nil)
+			req.Header.Del("User-Agent")
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			// Act
+			agent := detectAgent(req)
+
+			// Assert
+			assert.Equal(t, tt.expected, agent)
+		})
+	}
+}
+
+func TestServer_HandleRequest_WithAgentAPI_ShouldRouteByAgent(t *testing.T) {
+	// Arrange
+	newTarget := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, name)
+		}))
+	}
+	codexTarget := newTarget("codex-api")
+	defer codexTarget.Close()
+	activeTarget := newTarget("active-api")
+	defer activeTarget.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "codex-api", URL: codexTarget.URL},
+			{ID: "active-api", URL: activeTarget.URL},
+		},
+		Settings: config.Settings{
+			ActiveAPI: "active-api",
+			AgentAPIs: map[string]string{AgentCodex: "codex-api"},
+		},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	get := func(userAgent string) string {
+		req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/v1/models", server.GetPort()), nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// Act & Assert
+	assert.Equal(t, "codex-api", get("codex_cli_rs/0.20.0"))
+	assert.Equal(t, "active-api", get("claude-cli/1.0.83 (external, cli)"))
+
+	// Act & Assert - switching one agent live leaves the others alone
+	client := NewAdminClient(server.AdminAddr(), server.AdminToken())
+	require.NoError(t, client.SwitchAgentAPI(AgentClaudeCode, "codex-api"))
+	assert.Equal(t, "codex-api", get("claude-cli/1.0.83 (external, cli)"))
+	assert.Equal(t, "active-api", get("curl/8.4.0"))
+	assert.Error(t, client.SwitchAgentAPI("cursor", "codex-api"))
+}
@@ -0,0 +1,384 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicRequest is the subset of an Anthropic Messages request that is
+// carried over when translating to another protocol
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	Messages      []anthropicMessage   `json:"messages"`
+	System        json.RawMessage      `json:"system,omitempty"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	TopK          *int                 `json:"top_k,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Metadata      *anthropicMetadata   `json:"metadata,omitempty"`
+}
+
+// anthropicMessage is one turn of a conversation. Content is either a
+// string or a list of content blocks.
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// anthropicBlock is a request content block of any type
+type anthropicBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// anthropicImageSource holds inline base64 data or a URL
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// anthropicResponse is a complete, non-streamed Anthropic message
+type anthropicResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []interface{}  `json:"content"`
+	StopReason   *string        `json:"stop_reason"`
+	StopSequence *string        `json:"stop_sequence"`
+	Usage        anthropicUsage `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+}
+
+type anthropicTextBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type anthropicToolUseBlock struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// anthropicEvent is a streamed message event. Only the fields of its type
+// are set.
+type anthropicEvent struct {
+	Type         string             `json:"type"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	Index        *int               `json:"index,omitempty"`
+	ContentBlock interface{}        `json:"content_block,omitempty"`
+	Delta        interface{}        `json:"delta,omitempty"`
+	Usage        *anthropicUsage    `json:"usage,omitempty"`
+}
+
+// anthropicBlockDelta is an increment of a text or tool_use block
+type anthropicBlockDelta struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+}
+
+// anthropicMessageDelta carries the outcome of a streamed message
+type anthropicMessageDelta struct {
+	StopReason   string  `json:"stop_reason"`
+	StopSequence *string `json:"stop_sequence"`
+}
+
+// anthropicErrorBody is the body of an Anthropic error response or event
+type anthropicErrorBody struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicBlocks decodes message content, turning plain string content
+// into a single text block
+func anthropicBlocks(content json.RawMessage) ([]anthropicBlock, error) {
+	if len(content) == 0 || string(content) == "null" {
+		return nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return []anthropicBlock{{Type: "text", Text: text}}, nil
+	}
+
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil, fmt.Errorf("invalid message content: %w", err)
+	}
+	return blocks, nil
+}
+
+// anthropicText returns the text of a system prompt or tool result, which
+// may be a string or a list of blocks. Blocks other than text are ignored.
+func anthropicText(content json.RawMessage) (string, error) {
+	blocks, err := anthropicBlocks(content)
+	if err != nil {
+		return "", err
+	}
+
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// toolInput returns JSON arguments as a tool_use input object, falling back
+// to an empty object for arguments that are missing or malformed
+func toolInput(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage(`{}`)
+	}
+	return json.RawMessage(arguments)
+}
+
+// anthropicEvents assembles an Anthropic message stream. Protocol
+// converters report text, tool calls and the outcome, and it emits the
+// message_start, content_block_* and message_* events in order.
+type anthropicEvents struct {
+	id         string
+	model      string
+	started    bool
+	stopped    bool
+	blockIndex int
+	// openBlock is the type of the content block being streamed, if any
+	openBlock  string
+	stopReason string
+	usage      anthropicUsage
+}
+
+// start emits message_start once, before anything else
+func (e *anthropicEvents) start(out []byte) []byte {
+	if e.started {
+		return out
+	}
+	e.started = true
+
+	return append(out, sseEvent("message_start", anthropicEvent{
+		Type: "message_start",
+		Message: &anthropicResponse{
+			ID:      e.id,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   e.model,
+			Content: []interface{}{},
+			Usage:   anthropicUsage{InputTokens: e.usage.InputTokens},
+		},
+	})...)
+}
+
+// index returns the index of the current content block for an event
+func (e *anthropicEvents) index() *int {
+	index := e.blockIndex
+	return &index
+}
+
+// closeBlock ends the content block being streamed, if any
+func (e *anthropicEvents) closeBlock(out []byte) []byte {
+	if e.openBlock == "" {
+		return out
+	}
+	out = append(out, sseEvent("content_block_stop", anthropicEvent{Type: "content_block_stop", Index: e.index()})...)
+	e.openBlock = ""
+	e.blockIndex++
+	return out
+}
+
+// text streams a piece of text, opening a text block when needed
+func (e *anthropicEvents) text(out []byte, text string) []byte {
+	out = e.start(out)
+	if e.openBlock != "text" {
+		out = e.closeBlock(out)
+		e.openBlock = "text"
+		out = append(out, sseEvent("content_block_start", anthropicEvent{
+			Type:         "content_block_start",
+			Index:        e.index(),
+			ContentBlock: anthropicTextBlock{Type: "text", Text: ""},
+		})...)
+	}
+	return append(out, sseEvent("content_block_delta", anthropicEvent{
+		Type:  "content_block_delta",
+		Index: e.index(),
+		Delta: anthropicBlockDelta{Type: "text_delta", Text: text},
+	})...)
+}
+
+// toolUse opens a tool_use block for a new tool call
+func (e *anthropicEvents) toolUse(out []byte, id, name string) []byte {
+	out = e.start(out)
+	out = e.closeBlock(out)
+	e.openBlock = "tool_use"
+	return append(out, sseEvent("content_block_start", anthropicEvent{
+		Type:         "content_block_start",
+		Index:        e.index(),
+		ContentBlock: anthropicToolUseBlock{Type: "tool_use", ID: id, Name: name, Input: json.RawMessage(`{}`)},
+	})...)
+}
+
+// toolInput streams a fragment of the open tool call's JSON input
+func (e *anthropicEvents) toolInput(out []byte, partial string) []byte {
+	if e.openBlock != "tool_use" || partial == "" {
+		return out
+	}
+	return append(out, sseEvent("content_block_delta", anthropicEvent{
+		Type:  "content_block_delta",
+		Index: e.index(),
+		Delta: anthropicBlockDelta{Type: "input_json_delta", PartialJSON: partial},
+	})...)
+}
+
+// fail emits an error event, which ends the stream for the client
+func (e *anthropicEvents) fail(out []byte, errorType, message string) []byte {
+	e.stopped = true
+	return append(out, sseEvent("error", anthropicError(errorType, message))...)
+}
+
+// stop closes the stream with message_delta and message_stop. It is safe
+// to call more than once.
+func (e *anthropicEvents) stop(out []byte) []byte {
+	if e.stopped {
+		return out
+	}
+	out = e.start(out)
+	out = e.closeBlock(out)
+	e.stopped = true
+
+	stopReason := e.stopReason
+	if stopReason == "" {
+		stopReason = "end_turn"
+	}
+	usage := e.usage
+	out = append(out, sseEvent("message_delta", anthropicEvent{
+		Type:  "message_delta",
+		Delta: anthropicMessageDelta{StopReason: stopReason},
+		Usage: &usage,
+	})...)
+	return append(out, sseEvent("message_stop", anthropicEvent{Type: "message_stop"})...)
+}
+
+// anthropicError builds an Anthropic error body
+func anthropicError(errorType, message string) anthropicErrorBody {
+	body := anthropicErrorBody{Type: "error"}
+	body.Error.Type = errorType
+	body.Error.Message = message
+	return body
+}
+
+// anthropicErrorType maps an HTTP status to the matching Anthropic error type
+func anthropicErrorType(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return "invalid_request_error"
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status == http.StatusForbidden:
+		return "permission_error"
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == 529:
+		return "overloaded_error"
+	case status >= 400 && status < 500:
+		return "invalid_request_error"
+	}
+	return "api_error"
+}
+
+// anthropicProvider talks to the Anthropic Messages API
+type anthropicProvider struct {
+	baseProvider
+}
+
+func (p anthropicProvider) ParseModels(body []byte) ([]string, error) {
+	return parseModelIDs(body)
+}
+
+// Usage reads the usage of a message, a message_start event or a
+// message_delta event. Cached prompt tokens count as input.
+func (p anthropicProvider) Usage(payload []byte) (TokenUsage, bool) {
+	type usage struct {
+		InputTokens              *int64 `json:"input_tokens"`
+		OutputTokens             *int64 `json:"output_tokens"`
+		CacheReadInputTokens     int64  `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int64  `json:"cache_creation_input_tokens"`
+	}
+	var body struct {
+		Usage   *usage `json:"usage"`
+		Message *struct {
+			Usage *usage `json:"usage"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return TokenUsage{}, false
+	}
+
+	found := body.Usage
+	if found == nil && body.Message != nil {
+		found = body.Message.Usage
+	}
+	if found == nil || (found.InputTokens == nil && found.OutputTokens == nil) {
+		return TokenUsage{}, false
+	}
+
+	var tokens TokenUsage
+	if found.InputTokens != nil {
+		tokens.InputTokens = *found.InputTokens + found.CacheReadInputTokens + found.CacheCreationInputTokens
+	}
+	if found.OutputTokens != nil {
+		tokens.OutputTokens = *found.OutputTokens
+	}
+	return tokens, true
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"octopus-cli/internal/config"
+)
+
+// Supported values for config.APIConfig.AuthStyle
+const (
+	AuthStyleBearer      = "bearer"       // Authorization: Bearer <key>
+	AuthStyleAnthropic   = "anthropic"    // x-api-key plus anthropic-version
+	AuthStyleGoogle      = "google"       // x-goog-api-key
+	AuthStyleGoogleQuery = "google-query" // key=<key> query parameter
+	AuthStyleAzure       = "azure"        // api-key
+	AuthStyleNone        = "none"         // client credentials pass through
+)
+
+// defaultAnthropicVersion is sent when the client did not pick a version
+const defaultAnthropicVersion = "2023-06-01"
+
+// credentialHeaders lists headers that may carry a client-supplied key
+var credentialHeaders = []string{
+	"Authorization",
+	"X-Api-Key",
+	"X-Goog-Api-Key",
+	"Api-Key",
+}
+
+// applyAuth injects the credentials of api into req the way its provider
+// expects them
+func applyAuth(req *http.Request, api *config.APIConfig) error {
+	provider, err := ProviderFor(api)
+	if err != nil {
+		return err
+	}
+	return provider.ApplyAuth(req, api)
+}
+
+// applyAuthStyle injects the API key of api into req according to its auth
+// style, or defaultStyle when none is configured. Client-supplied
+// credentials are stripped first so the agent's dummy key never reaches
+// the upstream, even when no key is configured. Only the "none" style
+// passes the client's credentials through untouched.
+func applyAuthStyle(req *http.Request, api *config.APIConfig, defaultStyle string) error {
+	style := api.AuthStyle
+	if style == "" {
+		style = defaultStyle
+	}
+
+	switch style {
+	case AuthStyleBearer, AuthStyleAnthropic, AuthStyleGoogle, AuthStyleGoogleQuery, AuthStyleAzure, AuthStyleNone:
+	default:
+		return fmt.Errorf("unknown auth_style '%s' for API '%s'", api.AuthStyle, api.ID)
+	}
+
+	if style == AuthStyleNone {
+		return nil
+	}
+
+	stripClientCredentials(req)
+	if api.APIKey == "" {
+		return nil
+	}
+
+	switch style {
+	case AuthStyleBearer:
+		req.Header.Set("Authorization", "Bearer "+api.APIKey)
+	case AuthStyleAnthropic:
+		req.Header.Set("X-Api-Key", api.APIKey)
+		if req.Header.Get("Anthropic-Version") == "" {
+			req.Header.Set("Anthropic-Version", defaultAnthropicVersion)
+		}
+	case AuthStyleGoogle:
+		req.Header.Set("X-Goog-Api-Key", api.APIKey)
+	case AuthStyleGoogleQuery:
+		query := req.URL.Query()
+		query.Set("key", api.APIKey)
+		req.URL.RawQuery = query.Encode()
+	case AuthStyleAzure:
+		req.Header.Set("Api-Key", api.APIKey)
+	}
+
+	return nil
+}
+
+// stripClientCredentials removes every credential the client may have sent
+func stripClientCredentials(req *http.Request) {
+	for _, name := range credentialHeaders {
+		req.Header.Del(name)
+	}
+
+	query := req.URL.Query()
+	if _, ok := query["key"]; ok {
+		query.Del("key")
+		req.URL.RawQuery = query.Encode()
+	}
+}
@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestApplyAuth_WithEachStyle_ShouldInjectKeyAndStripClientCredentials(t *testing.T) {
+	tests := []struct {
+		style         string
+		expectHeaders map[string]string
+		expectQuery   string
+	}{
+		{
+			style:         "",
+			expectHeaders: map[string]string{"Authorization": "Bearer real-key"},
+			expectQuery:   "beta=true",
+		},
+		{
+			style:         AuthStyleAnthropic,
+			expectHeaders: map[string]string{"X-Api-Key": "real-key", "Anthropic-Version": defaultAnthropicVersion},
+			expectQuery:   "beta=true",
+		},
+		{
+			style:         AuthStyleGoogle,
+			expectHeaders: map[string]string{"X-Goog-Api-Key": "real-key"},
+			expectQuery:   "beta=true",
+		},
+		{
+			style:       AuthStyleGoogleQuery,
+			expectQuery: "beta=true&key=real-key",
+		},
+		{
+			style:         AuthStyleAzure,
+			expectHeaders: map[string]string{"Api-Key": "real-key"},
+			expectQuery:   "beta=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			// Arrange - the agent sends a dummy key in every possible place
+			req := httptest.NewRequest("POST", "/v1/messages?beta=true&key=dummy", nil)
+			req.Header.Set("Authorization", "Bearer dummy")
+			req.Header.Set("X-Api-Key", "dummy")
+			req.Header.Set("X-Goog-Api-Key", "dummy")
+			req.Header.Set("Api-Key", "dummy")
+			api := &config.APIConfig{ID: "api", APIKey: "real-key", AuthStyle: tt.style}
+
+			// Act
+			err := applyAuth(req, api)
+
+			// Assert
+			require.NoError(t, err)
+			for _, name := range credentialHeaders {
+				assert.Equal(t, tt.expectHeaders[name], req.Header.Get(name), name)
+			}
+			assert.Equal(t, tt.expectQuery, req.URL.RawQuery)
+		})
+	}
+}
+
+func TestApplyAuth_WithGeminiProtocolAndNoStyle_ShouldUseGoogleKeyHeader(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/v1beta/models/gemini-2.5-pro:generateContent", nil)
+	api := &config.APIConfig{ID: "api", APIKey: "real-key", Protocol: ProtocolGemini}
+
+	// Act
+	err := applyAuth(req, api)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "real-key", req.Header.Get("X-Goog-Api-Key"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestApplyAuth_WithClientAnthropicVersion_ShouldKeepIt(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/v1/messages", nil)
+	req.Header.Set("Anthropic-Version", "2024-01-01")
+	api := &config.APIConfig{ID: "api", APIKey: "real-key", AuthStyle: AuthStyleAnthropic}
+
+	// Act
+	err := applyAuth(req, api)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01", req.Header.Get("Anthropic-Version"))
+}
+
+func TestApplyAuth_WithoutConfiguredKey_ShouldStripClientCredentials(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/v1/messages?key=client-key", nil)
+	req.Header.Set("X-Api-Key", "client-key")
+	req.Header.Set("Authorization", "Bearer client-key")
+	api := &config.APIConfig{ID: "api", AuthStyle: AuthStyleAnthropic}
+
+	// Act
+	err := applyAuth(req, api)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("X-Api-Key"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Empty(t, req.URL.Query().Get("key"))
+}
+
+func TestApplyAuth_WithNoneStyle_ShouldPassClientCredentialsThrough(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/v1/messages?key=client-key", nil)
+	req.Header.Set("X-Api-Key", "client-key")
+	api := &config.APIConfig{ID: "api", APIKey: "real-key", AuthStyle: AuthStyleNone}
+
+	// Act
+	err := applyAuth(req, api)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "client-key", req.Header.Get("X-Api-Key"))
+	assert.Equal(t, "client-key", req.URL.Query().Get("key"))
+}
+
+func TestApplyAuth_WithUnknownStyle_ShouldReturnError(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/v1/messages", nil)
+	api := &config.APIConfig{ID: "api", APIKey: "real-key", AuthStyle: "basic"}
+
+	// Act
+	err := applyAuth(req, api)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown auth_style")
+}
+
+func TestServer_HandleRequest_WithAnthropicAuthStyle_ShouldNotLeakDummyKey(t *testing.T) {
+	// Arrange
+	received := make(chan http.Header, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "target", URL: targetServer.URL, APIKey: "sk-ant-real", AuthStyle: AuthStyleAnthropic},
+		},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Api-Key", "dummy")
+	req.Header.Set("Authorization", "Bearer dummy")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	headers := <-received
+	assert.Equal(t, "sk-ant-real", headers.Get("X-Api-Key"))
+	assert.Empty(t, headers.Get("Authorization"))
+	assert.Equal(t, defaultAnthropicVersion, headers.Get("Anthropic-Version"))
+}
@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"octopus-cli/internal/config"
+)
+
+// defaultAzureAPIVersion is the api-version sent when none is configured
+const defaultAzureAPIVersion = "2024-10-21"
+
+// errNoDeployment reports a request whose deployment cannot be chosen
+var errNoDeployment = errors.New("request names no model to pick an Azure deployment")
+
+// azureOperations maps OpenAI endpoints to the operation of an Azure
+// deployment serving them
+var azureOperations = []struct {
+	suffix    string
+	operation string
+}{
+	{"/v1/chat/completions", "chat/completions"},
+	{"/v1/completions", "completions"},
+	{"/v1/embeddings", "embeddings"},
+}
+
+// azureOpenAIProvider talks to Azure OpenAI, which speaks the OpenAI
+// protocol but takes its key in an api-key header, addresses models by
+// deployment and versions every call with an api-version parameter
+type azureOpenAIProvider struct {
+	openAIProvider
+}
+
+// azureAPIVersion returns the api-version of api
+func azureAPIVersion(api *config.APIConfig) string {
+	if api.APIVersion != "" {
+		return api.APIVersion
+	}
+	return defaultAzureAPIVersion
+}
+
+// azureDeployment returns the deployment serving model, which defaults to
+// a deployment named like the model
+func azureDeployment(api *config.APIConfig, model string) string {
+	if deployment, ok := api.Deployments[model]; ok {
+		return deployment
+	}
+	return model
+}
+
+// withAPIVersion adds the api-version parameter of api to u unless the
+// client already picked one
+func withAPIVersion(u *url.URL, api *config.APIConfig) {
+	query := u.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", azureAPIVersion(api))
+		u.RawQuery = query.Encode()
+	}
+}
+
+func (p azureOpenAIProvider) ModelsURL(api *config.APIConfig) (string, error) {
+	target, err := p.openAIProvider.ModelsURL(api)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	withAPIVersion(u, api)
+	return u.String(), nil
+}
+
+// adaptRequest sends OpenAI requests to the deployment of their model
+func (p azureOpenAIProvider) adaptRequest(r *http.Request, body []byte, api *config.APIConfig) (*http.Request, error) {
+	adapted := r.Clone(r.Context())
+	for _, op := range azureOperations {
+		if !strings.HasSuffix(r.URL.Path, op.suffix) {
+			continue
+		}
+		model := requestModel(&requestBody{data: body})
+		if model == "" {
+			return nil, errNoDeployment
+		}
+		adapted.URL.Path = strings.TrimSuffix(r.URL.Path, op.suffix) +
+			"/openai/deployments/" + url.PathEscape(azureDeployment(api, model)) + "/" + op.operation
+		adapted.URL.RawPath = ""
+		break
+	}
+	withAPIVersion(adapted.URL, api)
+	return adapted, nil
+}
+
+// adaptResponse normalizes Azure's error payloads into the OpenAI format.
+// Azure omits the error type, may send the code as a number, and its API
+// gateway answers with a flat {"statusCode", "message"} object.
+func (p azureOpenAIProvider) adaptResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	data, err := readResponseBody(resp)
+	if err != nil {
+		return err
+	}
+
+	var payload struct {
+		Error *struct {
+			Type       string          `json:"type"`
+			Code       json.RawMessage `json:"code"`
+			Param      json.RawMessage `json:"param"`
+			InnerError json.RawMessage `json:"innererror,omitempty"`
+		} `json:"error"`
+	}
+	out := openAIErrorBody{}
+	out.Error.Message = upstreamErrorMessage(data, resp.StatusCode)
+	out.Error.Type = openAIErrorType(resp.StatusCode)
+	if json.Unmarshal(data, &payload) == nil && payload.Error != nil {
+		if payload.Error.Type != "" {
+			out.Error.Type = payload.Error.Type
+		}
+		out.Error.Code = jsonString(payload.Error.Code)
+		out.Error.Param = jsonString(payload.Error.Param)
+		out.Error.InnerError = payload.Error.InnerError
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	replaceJSONBody(resp, converted)
+	return nil
+}
+
+// jsonString reads a JSON string or number as a string, returning nil for
+// anything else
+func jsonString(raw json.RawMessage) *string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return &text
+	}
+	var number json.Number
+	if json.Unmarshal(raw, &number) == nil {
+		text = number.String()
+		return &text
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestServer_HandleRequest_WithAzureProvider_ShouldCallDeploymentOfModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "openai client",
+			path:     "/v1/chat/completions",
+			body:     `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`,
+			expected: `"object":"chat.completion"`,
+		},
+		{
+			name:     "anthropic client",
+			path:     "/v1/messages",
+			body:     `{"model":"gpt-4o","max_tokens":32,"messages":[{"role":"user","content":"Hi"}]}`,
+			expected: `"type":"message"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			type upstreamCall struct {
+				path, apiVersion, apiKey, authorization string
+			}
+			calls := make(chan upstreamCall, 1)
+			targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls <- upstreamCall{r.URL.Path, r.URL.Query().Get("api-version"), r.Header.Get("Api-Key"), r.Header.Get("Authorization")}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-2024-08-06","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1}}`)
+			}))
+			defer targetServer.Close()
+
+			cfg := &config.Config{
+				Server: config.ServerConfig{Port: 0},
+				APIs: []config.APIConfig{{
+					ID:          "azure",
+					URL:         targetServer.URL,
+					APIKey:      "azure-key",
+					Provider:    ProviderAzureOpenAI,
+					APIVersion:  "2025-01-01-preview",
+					Deployments: map[string]string{"gpt-4o": "gpt4o-prod"},
+				}},
+				Settings: config.Settings{ActiveAPI: "azure"},
+			}
+			server := NewServer(cfg)
+			require.NoError(t, server.Start())
+			defer server.Stop()
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d%s", server.GetPort(), tt.path), strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer dummy")
+
+			// Act
+			resp, err := http.DefaultClient.Do(req)
+
+			// Assert
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Contains(t, string(body), tt.expected)
+
+			call := <-calls
+			assert.Equal(t, "/openai/deployments/gpt4o-prod/chat/completions", call.path)
+			assert.Equal(t, "2025-01-01-preview", call.apiVersion)
+			assert.Equal(t, "azure-key", call.apiKey)
+			assert.Empty(t, call.authorization)
+		})
+	}
+}
+
+func TestAzureOpenAIProvider_AdaptResponse_ShouldNormalizeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{
+			name:     "numeric code without type",
+			status:   http.StatusTooManyRequests,
+			body:     `{"error":{"code":429,"message":"Requests to the ChatCompletions_Create Operation have exceeded the rate limit."}}`,
+			expected: `{"error":{"message":"Requests to the ChatCompletions_Create Operation have exceeded the rate limit.","type":"rate_limit_error","param":null,"code":"429"}}`,
+		},
+		{
+			name:     "content filter",
+			status:   http.StatusBadRequest,
+			body:     `{"error":{"message":"The response was filtered","type":null,"param":"prompt","code":"content_filter","status":400,"innererror":{"code":"ResponsibleAIPolicyViolation"}}}`,
+			expected: `{"error":{"message":"The response was filtered","type":"invalid_request_error","param":"prompt","code":"content_filter","innererror":{"code":"ResponsibleAIPolicyViolation"}}}`,
+		},
+		{
+			name:     "api gateway",
+			status:   http.StatusUnauthorized,
+			body:     `{"statusCode":401,"message":"Access denied due to invalid subscription key."}`,
+			expected: `{"error":{"message":"Access denied due to invalid subscription key.","type":"authentication_error","param":null,"code":null}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			// Act
+			err := azureOpenAIProvider{}.adaptResponse(resp)
+
+			// Assert
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.JSONEq(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestAzureOpenAIProvider_ModelsURL_ShouldCarryAPIVersion(t *testing.T) {
+	// Arrange
+	provider, ok := LookupProvider(ProviderAzureOpenAI)
+	require.True(t, ok)
+
+	// Act
+	target, err := provider.ModelsURL(&config.APIConfig{URL: "https://res.openai.azure.com"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "https://res.openai.azure.com/openai/models?api-version="+defaultAzureAPIVersion, target)
+}
@@ -0,0 +1,271 @@
+package proxy
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// Load balancing strategies for the active group
+const (
+	BalanceRoundRobin     = "round-robin"
+	BalanceWeightedRandom = "weighted-random"
+	BalanceLeastInFlight  = "least-in-flight"
+	BalanceLowestLatency  = "lowest-latency"
+)
+
+// latencySmoothing is the weight of the newest sample in the latency average
+const latencySmoothing = 0.2
+
+// failedAttemptLatency is the latency a failed request without a response
+// counts as when ranking members, so that an unreachable member loses its
+// place instead of looking unmeasured
+const failedAttemptLatency = 30 * time.Second
+
+// groupMember is an API of the active group with its weight
+type groupMember struct {
+	api    config.APIConfig
+	weight int
+}
+
+// effectiveWeight returns the member's weight, defaulting to 1
+func (m groupMember) effectiveWeight() int {
+	if m.weight > 0 {
+		return m.weight
+	}
+	return 1
+}
+
+// UpstreamStats represents request counters of a single API
+type UpstreamStats struct {
+	Requests   int64         `json:"requests"`
+	Errors     int64         `json:"errors"`
+	InFlight   int64         `json:"in_flight"`
+	AvgLatency time.Duration `json:"avg_latency"`
+	// Tokens reported by the upstream, as read by its provider
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	// Queued counts requests waiting for the API's rate limits
+	Queued int `json:"queued"`
+
+	// rankLatency averages latency like AvgLatency but also counts failed
+	// requests without a response as failedAttemptLatency
+	rankLatency time.Duration
+}
+
+// smoothLatency adds a sample to a moving average, zero meaning no samples
+func smoothLatency(avg, sample time.Duration) time.Duration {
+	if avg == 0 {
+		return sample
+	}
+	return avg + time.Duration(latencySmoothing*float64(sample-avg))
+}
+
+// upstreamTracker keeps per-API request counters and a moving average of
+// the time until response headers arrive
+type upstreamTracker struct {
+	mu    sync.Mutex
+	stats map[string]*UpstreamStats
+}
+
+// newUpstreamTracker creates an empty upstream tracker
+func newUpstreamTracker() *upstreamTracker {
+	return &upstreamTracker{stats: make(map[string]*UpstreamStats)}
+}
+
+// get returns the counters of an API, creating them on first use.
+// The caller must hold t.mu.
+func (t *upstreamTracker) get(apiID string) *UpstreamStats {
+	stats, ok := t.stats[apiID]
+	if !ok {
+		stats = &UpstreamStats{}
+		t.stats[apiID] = stats
+	}
+	return stats
+}
+
+// begin records the start of a request to an API. The returned function
+// must be called once the request is over, with the time it took to get
+// response headers (zero if there was no response) and whether it failed.
+func (t *upstreamTracker) begin(apiID string) func(latency time.Duration, failed bool) {
+	t.mu.Lock()
+	stats := t.get(apiID)
+	stats.Requests++
+	stats.InFlight++
+	t.mu.Unlock()
+
+	return func(latency time.Duration, failed bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		stats.InFlight--
+		if failed {
+			stats.Errors++
+		}
+		if latency > 0 {
+			stats.AvgLatency = smoothLatency(stats.AvgLatency, latency)
+			stats.rankLatency = smoothLatency(stats.rankLatency, latency)
+		} else if failed {
+			stats.rankLatency = smoothLatency(stats.rankLatency, failedAttemptLatency)
+		}
+	}
+}
+
+// addUsage adds the tokens of a response to the counters of an API
+func (t *upstreamTracker) addUsage(apiID string, usage TokenUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.get(apiID)
+	stats.InputTokens += usage.InputTokens
+	stats.OutputTokens += usage.OutputTokens
+}
+
+// inFlight returns the number of requests currently sent to an API
+func (t *upstreamTracker) inFlight(apiID string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(apiID).InFlight
+}
+
+// rankLatency returns the average latency of an API counting failures
+// without a response, zero if unmeasured
+func (t *upstreamTracker) rankLatency(apiID string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(apiID).rankLatency
+}
+
+// remove drops the counters of an API. Requests still in flight update
+// counters that are no longer reported.
+func (t *upstreamTracker) remove(apiID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.stats, apiID)
+}
+
+// snapshot returns a copy of the counters of every API
+func (t *upstreamTracker) snapshot() map[string]UpstreamStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := make(map[string]UpstreamStats, len(t.stats))
+	for id, stats := range t.stats {
+		snapshot[id] = *stats
+	}
+	return snapshot
+}
+
+// balancer picks one member of the active group per request
+type balancer struct {
+	mu        sync.Mutex
+	upstreams *upstreamTracker
+	current   map[string]int
+	rand      *rand.Rand
+}
+
+// newBalancer creates a balancer reading load from the given tracker
+func newBalancer(upstreams *upstreamTracker) *balancer {
+	return &balancer{
+		upstreams: upstreams,
+		current:   make(map[string]int),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// pick selects a member of the group with the given strategy
+func (b *balancer) pick(members []groupMember, strategy string) (*config.APIConfig, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("active group has no configured APIs")
+	}
+
+	var picked groupMember
+	switch strategy {
+	case "", BalanceRoundRobin:
+		picked = b.roundRobin(members)
+	case BalanceWeightedRandom:
+		picked = b.weightedRandom(members)
+	case BalanceLeastInFlight:
+		picked = b.leastInFlight(members)
+	case BalanceLowestLatency:
+		picked = b.lowestLatency(members)
+	default:
+		return nil, fmt.Errorf("unknown balance_strategy %q", strategy)
+	}
+
+	api := picked.api
+	return &api, nil
+}
+
+// roundRobin implements smooth weighted round-robin, which interleaves
+// members instead of sending bursts to the heaviest one
+func (b *balancer) roundRobin(members []groupMember) groupMember {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := 0
+	best := -1
+	for i, member := range members {
+		weight := member.effectiveWeight()
+		total += weight
+		b.current[member.api.ID] += weight
+		if best < 0 || b.current[member.api.ID] > b.current[members[best].api.ID] {
+			best = i
+		}
+	}
+
+	b.current[members[best].api.ID] -= total
+	return members[best]
+}
+
+// weightedRandom picks a member at random in proportion to its weight
+func (b *balancer) weightedRandom(members []groupMember) groupMember {
+	total := 0
+	for _, member := range members {
+		total += member.effectiveWeight()
+	}
+
+	b.mu.Lock()
+	n := b.rand.Intn(total)
+	b.mu.Unlock()
+
+	for _, member := range members {
+		n -= member.effectiveWeight()
+		if n < 0 {
+			return member
+		}
+	}
+	return members[len(members)-1]
+}
+
+// leastInFlight picks the member with the fewest in-flight requests
+// relative to its weight, preferring earlier members on ties
+func (b *balancer) leastInFlight(members []groupMember) groupMember {
+	best := members[0]
+	bestLoad := float64(b.upstreams.inFlight(best.api.ID)) / float64(best.effectiveWeight())
+	for _, member := range members[1:] {
+		load := float64(b.upstreams.inFlight(member.api.ID)) / float64(member.effectiveWeight())
+		if load < bestLoad {
+			best, bestLoad = member, load
+		}
+	}
+	return best
+}
+
+// lowestLatency picks the member with the lowest average latency. Members
+// without a measurement yet are tried first so every member gets measured,
+// and failures without a response count as a slow measurement.
+func (b *balancer) lowestLatency(members []groupMember) groupMember {
+	best := members[0]
+	bestLatency := b.upstreams.rankLatency(best.api.ID)
+	for _, member := range members[1:] {
+		latency := b.upstreams.rankLatency(member.api.ID)
+		if latency < bestLatency {
+			best, bestLatency = member, latency
+		}
+	}
+	return best
+}
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// newGroup builds group members from parallel lists of IDs and weights
+func newGroup(ids []string, weights []int) []groupMember {
+	members := make([]groupMember, len(ids))
+	for i, id := range ids {
+		members[i] = groupMember{api: config.APIConfig{ID: id}, weight: weights[i]}
+	}
+	return members
+}
+
+// pickMany picks n times and returns the picked IDs in order
+func pickMany(t *testing.T, b *balancer, members []groupMember, strategy string, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		api, err := b.pick(members, strategy)
+		require.NoError(t, err)
+		ids[i] = api.ID
+	}
+	return ids
+}
+
+func TestBalancer_RoundRobin_ShouldInterleaveByWeight(t *testing.T) {
+	// Arrange
+	b := newBalancer(newUpstreamTracker())
+	members := newGroup([]string{"a", "b"}, []int{3, 1})
+
+	// Act
+	ids := pickMany(t, b, members, BalanceRoundRobin, 8)
+
+	// Assert
+	assert.Equal(t, []string{"a", "a", "b", "a", "a", "a", "b", "a"}, ids)
+}
+
+func TestBalancer_RoundRobin_WithoutWeights_ShouldAlternate(t *testing.T) {
+	// Arrange
+	b := newBalancer(newUpstreamTracker())
+	members := newGroup([]string{"a", "b", "c"}, []int{0, 0, 0})
+
+	// Act
+	ids := pickMany(t, b, members, "", 6)
+
+	// Assert
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, ids)
+}
+
+func TestBalancer_WeightedRandom_ShouldFollowWeights(t *testing.T) {
+	// Arrange
+	b := newBalancer(newUpstreamTracker())
+	members := newGroup([]string{"a", "b"}, []int{9, 1})
+
+	// Act
+	counts := map[string]int{}
+	for _, id := range pickMany(t, b, members, BalanceWeightedRandom, 1000) {
+		counts[id]++
+	}
+
+	// Assert
+	assert.InDelta(t, 900, counts["a"], 60)
+	assert.InDelta(t, 100, counts["b"], 60)
+}
+
+func TestBalancer_LeastInFlight_ShouldPickIdlestMember(t *testing.T) {
+	// Arrange
+	upstreams := newUpstreamTracker()
+	b := newBalancer(upstreams)
+	members := newGroup([]string{"a", "b"}, []int{1, 1})
+	doneA := upstreams.begin("a")
+
+	// Act & Assert
+	api, err := b.pick(members, BalanceLeastInFlight)
+	require.NoError(t, err)
+	assert.Equal(t, "b", api.ID)
+
+	doneA(time.Millisecond, false)
+	api, err = b.pick(members, BalanceLeastInFlight)
+	require.NoError(t, err)
+	assert.Equal(t, "a", api.ID)
+}
+
+func TestBalancer_LowestLatency_ShouldPickFastestMember(t *testing.T) {
+	// Arrange
+	upstreams := newUpstreamTracker()
+	b := newBalancer(upstreams)
+	members := newGroup([]string{"slow", "fast"}, []int{1, 1})
+	upstreams.begin("slow")(200*time.Millisecond, false)
+	upstreams.begin("fast")(20*time.Millisecond, false)
+
+	// Act
+	api, err := b.pick(members, BalanceLowestLatency)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "fast", api.ID)
+}
+
+func TestBalancer_LowestLatency_WithUnreachableMember_ShouldPickMeasuredMember(t *testing.T) {
+	// Arrange
+	upstreams := newUpstreamTracker()
+	b := newBalancer(upstreams)
+	members := newGroup([]string{"dead", "slow"}, []int{1, 1})
+	upstreams.begin("dead")(0, true)
+	upstreams.begin("slow")(2*time.Second, false)
+
+	// Act
+	api, err := b.pick(members, BalanceLowestLatency)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "slow", api.ID)
+	assert.Zero(t, upstreams.snapshot()["dead"].AvgLatency, "failures do not skew the reported latency")
+}
+
+func TestBalancer_WithUnknownStrategy_ShouldReturnError(t *testing.T) {
+	// Arrange
+	b := newBalancer(newUpstreamTracker())
+
+	// Act
+	_, err := b.pick(newGroup([]string{"a"}, []int{1}), "fastest")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown balance_strategy")
+}
+
+func TestServer_HandleRequest_WithActiveGroup_ShouldSpreadRequests(t *testing.T) {
+	// Arrange
+	var callsA, callsB int64
+	targetA := newCountingTarget("a", http.StatusOK, &callsA)
+	defer targetA.Close()
+	targetB := newCountingTarget("b", http.StatusOK, &callsB)
+	defer targetB.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "a", URL: targetA.URL},
+			{ID: "b", URL: targetB.URL},
+		},
+		Settings: config.Settings{
+			ActiveGroup: []config.GroupMember{{ID: "a", Weight: 2}, {ID: "b"}, {ID: "missing"}},
+		},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	for i := 0; i < 6; i++ {
+		status, _ := postThroughProxy(t, server, "x")
+		require.Equal(t, http.StatusOK, status)
+	}
+
+	// Assert
+	assert.Equal(t, int64(4), atomic.LoadInt64(&callsA))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&callsB))
+
+	stats := server.GetStats()
+	assert.Equal(t, int64(4), stats.Upstreams["a"].Requests)
+	assert.Equal(t, int64(2), stats.Upstreams["b"].Requests)
+	assert.Eventually(t, func() bool {
+		a := server.GetStats().Upstreams["a"]
+		return a.InFlight == 0 && a.AvgLatency > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_GetActiveAPI_WithOpenCircuitInGroup_ShouldSkipMember(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		APIs: []config.APIConfig{{ID: "a"}, {ID: "b"}},
+		Settings: config.Settings{
+			ActiveGroup:             []config.GroupMember{{ID: "a"}, {ID: "b"}},
+			BreakerFailureThreshold: 1,
+		},
+	}
+	server := NewServer(cfg)
+	server.breakers.recordFailure("a")
+
+	// Act & Assert
+	for i := 0; i < 3; i++ {
+		api, err := server.getActiveAPI()
+		require.NoError(t, err)
+		assert.Equal(t, "b", api.ID)
+	}
+}
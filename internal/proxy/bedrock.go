@@ -0,0 +1,309 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// bedrockAnthropicVersion replaces the anthropic-version header on Bedrock
+const bedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// bedrockSigningName is the SigV4 service name of both Bedrock endpoints
+const bedrockSigningName = "bedrock"
+
+// anthropicToBedrock sends Anthropic Messages requests to Bedrock's
+// InvokeModel endpoints. The body stays in the Messages format, with the
+// model moved into the path.
+type anthropicToBedrock struct {
+	stream bool
+}
+
+func (t *anthropicToBedrock) translateRequest(r *http.Request, body []byte) (*http.Request, []byte, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, nil, fmt.Errorf("invalid Anthropic request: %w", err)
+	}
+
+	var model string
+	if err := json.Unmarshal(payload["model"], &model); err != nil || model == "" {
+		return nil, nil, fmt.Errorf("request names no model")
+	}
+	if raw, ok := payload["stream"]; ok {
+		if err := json.Unmarshal(raw, &t.stream); err != nil {
+			return nil, nil, fmt.Errorf("invalid stream field: %w", err)
+		}
+	}
+	delete(payload, "model")
+	delete(payload, "stream")
+
+	if _, ok := payload["anthropic_version"]; !ok {
+		payload["anthropic_version"] = json.RawMessage(`"` + bedrockAnthropicVersion + `"`)
+	}
+	// Bedrock takes beta flags in the body instead of a header
+	if betas := anthropicBetas(r.Header); len(betas) > 0 {
+		if _, ok := payload["anthropic_beta"]; !ok {
+			encoded, err := json.Marshal(betas)
+			if err != nil {
+				return nil, nil, err
+			}
+			payload["anthropic_beta"] = encoded
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	action, accept := "invoke", "application/json"
+	if t.stream {
+		action, accept = "invoke-with-response-stream", "application/vnd.amazon.eventstream"
+	}
+	translated := withTranslatedPath(r, "/v1/messages", "/model/"+model+"/"+action)
+	// Model IDs such as "anthropic.claude-sonnet-4-20250514-v1:0" are sent
+	// with their colon escaped, like the AWS SDKs do
+	translated.URL.RawPath = strings.TrimSuffix(r.URL.EscapedPath(), "/v1/messages") +
+		"/model/" + strings.ReplaceAll(url.PathEscape(model), ":", "%3A") + "/" + action
+	translated.URL.RawQuery = ""
+	translated.Header.Set("Accept", accept)
+	return translated, data, nil
+}
+
+// anthropicBetas splits the anthropic-beta headers of a request into flags
+func anthropicBetas(header http.Header) []string {
+	var betas []string
+	for _, value := range header.Values("Anthropic-Beta") {
+		for _, beta := range strings.Split(value, ",") {
+			if beta = strings.TrimSpace(beta); beta != "" {
+				betas = append(betas, beta)
+			}
+		}
+	}
+	return betas
+}
+
+func (t *anthropicToBedrock) translateResponse(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		data, err := readResponseBody(resp)
+		if err != nil {
+			return err
+		}
+		converted, err := json.Marshal(anthropicError(anthropicErrorType(resp.StatusCode), upstreamErrorMessage(data, resp.StatusCode)))
+		if err != nil {
+			return err
+		}
+		replaceJSONBody(resp, converted)
+		return nil
+	}
+
+	// A non-streamed response already is an Anthropic message
+	if !t.stream {
+		return nil
+	}
+
+	resp.Body = &bedrockStream{body: resp.Body}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	resp.Header.Set("Content-Type", "text/event-stream")
+	return nil
+}
+
+// bedrockStream turns Bedrock's event stream frames back into the
+// server-sent events of the Anthropic API
+type bedrockStream struct {
+	body    io.ReadCloser
+	pending []byte
+	err     error
+}
+
+func (s *bedrockStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+
+		msg, err := readEventStreamMessage(s.body)
+		if err != nil {
+			s.err = err
+			if err != io.EOF {
+				s.pending = sseEvent("error", anthropicError("api_error", "invalid Bedrock event stream: "+err.Error()))
+			}
+			continue
+		}
+		s.pending = bedrockEvent(msg)
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *bedrockStream) Close() error {
+	return s.body.Close()
+}
+
+// bedrockEvent converts a frame into the Anthropic event it carries.
+// Exceptions become error events; other frames are dropped.
+func bedrockEvent(msg eventStreamMessage) []byte {
+	if data, ok := bedrockChunk(msg); ok {
+		var event struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil || event.Type == "" {
+			return nil
+		}
+		return sseEvent(event.Type, json.RawMessage(data))
+	}
+
+	switch msg.Headers[":message-type"] {
+	case "exception":
+		exception := msg.Headers[":exception-type"]
+		return sseEvent("error", anthropicError(bedrockExceptionType(exception), upstreamErrorMessage(msg.Payload, 0)))
+	case "error":
+		return sseEvent("error", anthropicError("api_error", msg.Headers[":error-message"]))
+	}
+	return nil
+}
+
+// bedrockChunk returns the Anthropic event JSON of a chunk frame, which
+// Bedrock wraps as base64 in {"bytes": ...}
+func bedrockChunk(msg eventStreamMessage) ([]byte, bool) {
+	if msg.Headers[":message-type"] != "event" || msg.Headers[":event-type"] != "chunk" {
+		return nil, false
+	}
+	var chunk struct {
+		Bytes []byte `json:"bytes"`
+	}
+	if err := json.Unmarshal(msg.Payload, &chunk); err != nil || len(chunk.Bytes) == 0 {
+		return nil, false
+	}
+	return chunk.Bytes, true
+}
+
+// bedrockExceptionType maps a Bedrock stream exception to the matching
+// Anthropic error type
+func bedrockExceptionType(exception string) string {
+	switch exception {
+	case "throttlingException":
+		return "rate_limit_error"
+	case "serviceUnavailableException", "modelNotReadyException":
+		return "overloaded_error"
+	case "validationException":
+		return "invalid_request_error"
+	}
+	return "api_error"
+}
+
+// bedrockProvider talks to Claude on Amazon Bedrock, signing requests with
+// the AWS credentials of the API instead of an API key
+type bedrockProvider struct {
+	anthropicProvider
+}
+
+// BaseURL defaults to the Bedrock runtime endpoint of the API's region
+func (p bedrockProvider) BaseURL(api *config.APIConfig) (string, error) {
+	if api.URL != "" {
+		return api.URL, nil
+	}
+	region, err := awsRegion(api)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region), nil
+}
+
+func (p bedrockProvider) ApplyAuth(req *http.Request, api *config.APIConfig) error {
+	creds, err := awsCredentialsFor(api)
+	if err != nil {
+		return err
+	}
+	region, err := awsRegion(api)
+	if err != nil {
+		return err
+	}
+	body, err := requestPayload(req)
+	if err != nil {
+		return err
+	}
+
+	stripClientCredentials(req)
+	signV4(req, body, creds, region, bedrockSigningName, time.Now())
+	return nil
+}
+
+// ModelsURL lists the Anthropic foundation models of the region. Models
+// are listed by the Bedrock control plane, not the runtime endpoint.
+func (p bedrockProvider) ModelsURL(api *config.APIConfig) (string, error) {
+	base := api.URL
+	if base == "" {
+		region, err := awsRegion(api)
+		if err != nil {
+			return "", err
+		}
+		base = fmt.Sprintf("https://bedrock.%s.amazonaws.com", region)
+	}
+	target, err := joinURL(base, &url.URL{Path: "/foundation-models", RawQuery: "byProvider=anthropic"})
+	if err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+func (p bedrockProvider) ParseModels(body []byte) ([]string, error) {
+	var listing struct {
+		ModelSummaries []struct {
+			ModelID string `json:"modelId"`
+		} `json:"modelSummaries"`
+	}
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("invalid model listing: %w", err)
+	}
+
+	models := make([]string, 0, len(listing.ModelSummaries))
+	for _, model := range listing.ModelSummaries {
+		models = append(models, model.ModelID)
+	}
+	return models, nil
+}
+
+// Usage reads an Anthropic message, or a whole event stream whose chunks
+// carry Anthropic events
+func (p bedrockProvider) Usage(payload []byte) (TokenUsage, bool) {
+	if usage, ok := p.anthropicProvider.Usage(payload); ok {
+		return usage, true
+	}
+
+	var total TokenUsage
+	found := false
+	reader := bytes.NewReader(payload)
+	for {
+		msg, err := readEventStreamMessage(reader)
+		if err != nil {
+			break
+		}
+		data, ok := bedrockChunk(msg)
+		if !ok {
+			continue
+		}
+		usage, ok := p.anthropicProvider.Usage(data)
+		if !ok {
+			continue
+		}
+		found = true
+		if usage.InputTokens > 0 {
+			total.InputTokens = usage.InputTokens
+		}
+		if usage.OutputTokens > 0 {
+			total.OutputTokens = usage.OutputTokens
+		}
+	}
+	return total, found
+}
@@ -0,0 +1,354 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+const (
+	testAWSAccessKey = "AKIDEXAMPLE"
+	testAWSSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// eventStreamFrame encodes a frame of the AWS event stream with string headers
+func eventStreamFrame(headers map[string]string, payload []byte) []byte {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var encoded bytes.Buffer
+	for _, name := range names {
+		encoded.WriteByte(byte(len(name)))
+		encoded.WriteString(name)
+		encoded.WriteByte(eventStreamString)
+		binary.Write(&encoded, binary.BigEndian, uint16(len(headers[name])))
+		encoded.WriteString(headers[name])
+	}
+
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.BigEndian, uint32(12+encoded.Len()+len(payload)+4))
+	binary.Write(&frame, binary.BigEndian, uint32(encoded.Len()))
+	binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(frame.Bytes()))
+	frame.Write(encoded.Bytes())
+	frame.Write(payload)
+	binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(frame.Bytes()))
+	return frame.Bytes()
+}
+
+// bedrockChunkFrame wraps an Anthropic event the way Bedrock streams it
+func bedrockChunkFrame(event string) []byte {
+	payload, _ := json.Marshal(map[string][]byte{"bytes": []byte(event)})
+	return eventStreamFrame(map[string]string{
+		":event-type":   "chunk",
+		":content-type": "application/json",
+		":message-type": "event",
+	}, payload)
+}
+
+// verifySigV4 recomputes the signature of a received request from what
+// arrived on the wire
+func verifySigV4(r *http.Request, body []byte, secret string) error {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), awsSigningAlgorithm+" ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		key, value, _ := strings.Cut(part, "=")
+		fields[key] = value
+	}
+	scope := strings.SplitN(fields["Credential"], "/", 2)
+	if len(scope) != 2 {
+		return fmt.Errorf("malformed credential %q", fields["Credential"])
+	}
+	parts := strings.Split(scope[1], "/")
+
+	var headers strings.Builder
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	for i, segment := range segments {
+		segments[i] = awsURIEncode(segment)
+	}
+	payloadHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{r.Method, strings.Join(segments, "/"), awsCanonicalQuery(r.URL),
+		headers.String(), fields["SignedHeaders"], hex.EncodeToString(payloadHash[:])}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{awsSigningAlgorithm, r.Header.Get("X-Amz-Date"), scope[1],
+		hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secret)
+	for _, part := range append(parts[:3:3], "aws4_request", stringToSign) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if expected := hex.EncodeToString(key); expected != fields["Signature"] {
+		return fmt.Errorf("signature mismatch: got %s, expected %s", fields["Signature"], expected)
+	}
+	return nil
+}
+
+func TestSignV4_ShouldMatchAWSTestSuite(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+			// Act
+			signV4(req, nil, awsCredentials{AccessKeyID: testAWSAccessKey, SecretAccessKey: testAWSSecretKey}, "us-east-1", "service", now)
+
+			// Assert
+			assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+				"SignedHeaders=host;x-amz-date, Signature="+tt.signature, req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestServer_HandleRequest_WithBedrockProvider_ShouldSignAndConvertStream(t *testing.T) {
+	// Arrange
+	var stream bytes.Buffer
+	for _, event := range []string{
+		`{"type":"message_start","message":{"id":"msg_bdrk_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":12}}`,
+		`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":25,"outputTokenCount":12}}`,
+	} {
+		stream.Write(bedrockChunkFrame(event))
+	}
+
+	type upstreamCall struct {
+		path, rawPath string
+		body          []byte
+		signErr       error
+	}
+	calls := make(chan upstreamCall, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls <- upstreamCall{path: r.URL.Path, rawPath: r.URL.RawPath, body: body, signErr: verifySigV4(r, body, testAWSSecretKey)}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(stream.Bytes())
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:                 "bedrock",
+			URL:                targetServer.URL,
+			Provider:           ProviderBedrock,
+			Region:             "us-west-2",
+			AWSAccessKeyID:     testAWSAccessKey,
+			AWSSecretAccessKey: testAWSSecretKey,
+			AWSSessionToken:    "session-token",
+			ModelMap:           map[string]string{"claude-sonnet-4-20250514": "anthropic.claude-sonnet-4-20250514-v1:0"},
+		}},
+		Settings: config.Settings{ActiveAPI: "bedrock"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/v1/messages?beta=true", server.GetPort()),
+		strings.NewReader(`{"model":"claude-sonnet-4-20250514","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"Hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", "dummy")
+	req.Header.Set("Anthropic-Version", "2023-06-01")
+	req.Header.Set("Anthropic-Beta", "interleaved-thinking-2025-05-14, fine-grained-tool-streaming-2025-05-14")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	call := <-calls
+	assert.NoError(t, call.signErr)
+	assert.Equal(t, "/model/anthropic.claude-sonnet-4-20250514-v1:0/invoke-with-response-stream", call.path)
+	assert.Equal(t, "/model/anthropic.claude-sonnet-4-20250514-v1%3A0/invoke-with-response-stream", call.rawPath)
+	assert.JSONEq(t, `{
+		"anthropic_version": "bedrock-2023-05-31",
+		"anthropic_beta": ["interleaved-thinking-2025-05-14", "fine-grained-tool-streaming-2025-05-14"],
+		"max_tokens": 100,
+		"messages": [{"role":"user","content":"Hi"}]
+	}`, string(call.body))
+
+	assert.Equal(t, "event: message_start\n"+
+		`data: {"type":"message_start","message":{"id":"msg_bdrk_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"usage":{"input_tokens":25,"output_tokens":1}}}`+"\n\n"+
+		"event: content_block_start\n"+
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`+"\n\n"+
+		"event: content_block_delta\n"+
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`+"\n\n"+
+		"event: content_block_stop\n"+
+		`data: {"type":"content_block_stop","index":0}`+"\n\n"+
+		"event: message_delta\n"+
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":12}}`+"\n\n"+
+		"event: message_stop\n"+
+		`data: {"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":25,"outputTokenCount":12}}`+"\n\n", string(body))
+
+	assert.Eventually(t, func() bool {
+		stats := server.GetStats().Upstreams["bedrock"]
+		return stats.InputTokens == 25 && stats.OutputTokens == 12
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_HandleRequest_WithBedrockProvider_ShouldConvertErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response []byte
+		stream   bool
+		expected string
+	}{
+		{
+			name:     "error status",
+			status:   http.StatusTooManyRequests,
+			response: []byte(`{"message":"Too many requests, please wait before trying again."}`),
+			expected: `{"type":"error","error":{"type":"rate_limit_error","message":"Too many requests, please wait before trying again."}}`,
+		},
+		{
+			name:   "exception frame",
+			status: http.StatusOK,
+			response: eventStreamFrame(map[string]string{
+				":message-type":   "exception",
+				":exception-type": "modelStreamErrorException",
+				":content-type":   "application/json",
+			}, []byte(`{"message":"The model stopped unexpectedly"}`)),
+			stream: true,
+			expected: "event: error\n" +
+				`data: {"type":"error","error":{"type":"api_error","message":"The model stopped unexpectedly"}}` + "\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write(tt.response)
+			}))
+			defer targetServer.Close()
+
+			cfg := &config.Config{
+				Server: config.ServerConfig{Port: 0},
+				APIs: []config.APIConfig{{ID: "bedrock", URL: targetServer.URL, Provider: ProviderBedrock, Region: "us-east-1",
+					AWSAccessKeyID: testAWSAccessKey, AWSSecretAccessKey: testAWSSecretKey}},
+				Settings: config.Settings{ActiveAPI: "bedrock"},
+			}
+			server := NewServer(cfg)
+			require.NoError(t, server.Start())
+			defer server.Stop()
+
+			// Act
+			status, body := postThroughProxy(t, server, fmt.Sprintf(`{"model":"anthropic.claude-3-5-haiku-20241022-v1:0","stream":%t,"messages":[]}`, tt.stream))
+
+			// Assert
+			assert.Equal(t, tt.status, status)
+			if tt.stream {
+				assert.Equal(t, tt.expected, body)
+			} else {
+				assert.JSONEq(t, tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestReadEventStreamMessage_WithCorruptFrame_ShouldReturnChecksumError(t *testing.T) {
+	// Arrange
+	frame := bedrockChunkFrame(`{"type":"ping"}`)
+	frame[len(frame)-6] ^= 0xff
+
+	// Act
+	_, err := readEventStreamMessage(bytes.NewReader(frame))
+
+	// Assert
+	assert.ErrorIs(t, err, errEventStreamChecksum)
+}
+
+func TestAWSCredentialsFor_ShouldResolveStaticThenEnvThenSharedFile(t *testing.T) {
+	// Arrange
+	credentialsFile := filepath.Join(t.TempDir(), "credentials")
+	require.NoError(t, os.WriteFile(credentialsFile, []byte(
+		"[default]\naws_access_key_id = DEFAULTKEY\naws_secret_access_key = default-secret\n\n"+
+			"[work]\naws_access_key_id=WORKKEY\naws_secret_access_key=work-secret\naws_session_token=work-token\n"), 0600))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	// Act
+	static, staticErr := awsCredentialsFor(&config.APIConfig{AWSAccessKeyID: "STATICKEY", AWSSecretAccessKey: "static-secret"})
+	profile, profileErr := awsCredentialsFor(&config.APIConfig{AWSProfile: "work"})
+	env, envErr := awsCredentialsFor(&config.APIConfig{})
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	fallback, fallbackErr := awsCredentialsFor(&config.APIConfig{})
+	_, missingErr := awsCredentialsFor(&config.APIConfig{AWSProfile: "missing"})
+
+	// Assert
+	require.NoError(t, staticErr)
+	assert.Equal(t, awsCredentials{"STATICKEY", "static-secret", ""}, static)
+	require.NoError(t, profileErr)
+	assert.Equal(t, awsCredentials{"WORKKEY", "work-secret", "work-token"}, profile)
+	require.NoError(t, envErr)
+	assert.Equal(t, awsCredentials{"ENVKEY", "env-secret", ""}, env)
+	require.NoError(t, fallbackErr)
+	assert.Equal(t, awsCredentials{"DEFAULTKEY", "default-secret", ""}, fallback)
+	assert.Error(t, missingErr)
+}
+
+func TestBedrockProvider_ShouldDefaultToRegionalEndpoints(t *testing.T) {
+	// Arrange
+	provider := bedrockProvider{}
+	api := &config.APIConfig{ID: "bedrock", Provider: ProviderBedrock, Region: "eu-central-1"}
+
+	// Act
+	baseURL, baseErr := provider.BaseURL(api)
+	modelsURL, modelsErr := provider.ModelsURL(api)
+
+	// Assert
+	require.NoError(t, baseErr)
+	assert.Equal(t, "https://bedrock-runtime.eu-central-1.amazonaws.com", baseURL)
+	require.NoError(t, modelsErr)
+	assert.Equal(t, "https://bedrock.eu-central-1.amazonaws.com/foundation-models?byProvider=anthropic", modelsURL)
+}
@@ -0,0 +1,302 @@
+package proxy
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Circuit breaker defaults used when a threshold is not configured
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerErrorRate        = 0.5
+	defaultBreakerMinRequests      = 10
+	defaultBreakerWindow           = 60 * time.Second
+	defaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+// errCircuitOpen is returned when every API of a chain has an open circuit
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerStatus reports the state of the circuit breaker of one API
+type BreakerStatus struct {
+	APIID               string `json:"api_id"`
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Requests            int    `json:"requests"`
+	Failures            int    `json:"failures"`
+}
+
+// breakerSettings holds the thresholds shared by all breakers
+type breakerSettings struct {
+	failureThreshold int
+	errorRate        float64
+	minRequests      int
+	window           time.Duration
+	openTimeout      time.Duration
+}
+
+// breakerSettingsFor resolves the configured thresholds, applying defaults
+func breakerSettingsFor(settings config.Settings) breakerSettings {
+	bs := breakerSettings{
+		failureThreshold: defaultBreakerFailureThreshold,
+		errorRate:        defaultBreakerErrorRate,
+		minRequests:      defaultBreakerMinRequests,
+		window:           defaultBreakerWindow,
+		openTimeout:      defaultBreakerOpenTimeout,
+	}
+
+	if settings.BreakerFailureThreshold > 0 {
+		bs.failureThreshold = settings.BreakerFailureThreshold
+	}
+	if settings.BreakerErrorRate > 0 {
+		bs.errorRate = settings.BreakerErrorRate
+	}
+	if settings.BreakerMinRequests > 0 {
+		bs.minRequests = settings.BreakerMinRequests
+	}
+	if settings.BreakerWindow > 0 {
+		bs.window = time.Duration(settings.BreakerWindow) * time.Second
+	}
+	if settings.BreakerOpenTimeout > 0 {
+		bs.openTimeout = time.Duration(settings.BreakerOpenTimeout) * time.Second
+	}
+
+	return bs
+}
+
+// circuitBreaker tracks the health of a single API. It opens on too many
+// consecutive failures or a high error rate, and after the open timeout lets
+// one probe request through to decide whether to close again.
+type circuitBreaker struct {
+	settings breakerSettings
+	now      func() time.Time
+
+	state               string
+	consecutiveFailures int
+	requests            int
+	failures            int
+	windowStart         time.Time
+	openedAt            time.Time
+	probing             bool
+}
+
+// allow reports whether a request may be sent to the API
+func (b *circuitBreaker) allow() bool {
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.settings.openTimeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// recordSuccess records a successful request
+func (b *circuitBreaker) recordSuccess() {
+	if b.state == BreakerHalfOpen {
+		b.reset()
+		return
+	}
+
+	b.rollWindow()
+	b.requests++
+	b.consecutiveFailures = 0
+}
+
+// recordFailure records a failed request and opens the circuit when a
+// threshold is crossed
+func (b *circuitBreaker) recordFailure() {
+	if b.state == BreakerHalfOpen {
+		b.trip()
+		return
+	}
+
+	b.rollWindow()
+	b.requests++
+	b.failures++
+	b.consecutiveFailures++
+
+	if b.consecutiveFailures >= b.settings.failureThreshold {
+		b.trip()
+		return
+	}
+	if b.requests >= b.settings.minRequests &&
+		float64(b.failures)/float64(b.requests) >= b.settings.errorRate {
+		b.trip()
+	}
+}
+
+// release gives up a probe that ended without an outcome, such as a
+// request cancelled by the client
+func (b *circuitBreaker) release() {
+	b.probing = false
+}
+
+// trip opens the circuit
+func (b *circuitBreaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.probing = false
+}
+
+// reset closes the circuit and clears its counters
+func (b *circuitBreaker) reset() {
+	b.state = BreakerClosed
+	b.consecutiveFailures = 0
+	b.requests = 0
+	b.failures = 0
+	b.windowStart = b.now()
+	b.probing = false
+}
+
+// rollWindow starts a new error rate window when the current one expired
+func (b *circuitBreaker) rollWindow() {
+	if b.now().Sub(b.windowStart) >= b.settings.window {
+		b.windowStart = b.now()
+		b.requests = 0
+		b.failures = 0
+	}
+}
+
+// breakerSet holds the circuit breakers of all APIs, keyed by API ID
+type breakerSet struct {
+	mu       sync.Mutex
+	settings breakerSettings
+	breakers map[string]*circuitBreaker
+	now      func() time.Time
+}
+
+// newBreakerSet creates an empty breaker set with the given thresholds
+func newBreakerSet(settings breakerSettings) *breakerSet {
+	return &breakerSet{
+		settings: settings,
+		breakers: make(map[string]*circuitBreaker),
+		now:      time.Now,
+	}
+}
+
+// get returns the breaker of an API, creating it on first use.
+// The caller must hold s.mu.
+func (s *breakerSet) get(apiID string) *circuitBreaker {
+	b, ok := s.breakers[apiID]
+	if !ok {
+		b = &circuitBreaker{
+			settings:    s.settings,
+			now:         s.now,
+			state:       BreakerClosed,
+			windowStart: s.now(),
+		}
+		s.breakers[apiID] = b
+	}
+	return b
+}
+
+// allow reports whether a request may be sent to the API
+func (s *breakerSet) allow(apiID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(apiID).allow()
+}
+
+// rejecting reports whether the API's circuit would currently turn a
+// request away, without changing its state
+func (s *breakerSet) rejecting(apiID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[apiID]
+	if !ok {
+		return false
+	}
+	switch b.state {
+	case BreakerOpen:
+		return b.now().Sub(b.openedAt) < b.settings.openTimeout
+	case BreakerHalfOpen:
+		return b.probing
+	default:
+		return false
+	}
+}
+
+// recordSuccess records a successful request to the API
+func (s *breakerSet) recordSuccess(apiID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(apiID).recordSuccess()
+}
+
+// recordFailure records a failed request to the API
+func (s *breakerSet) recordFailure(apiID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(apiID).recordFailure()
+}
+
+// release gives up a probe of the API that ended without an outcome
+func (s *breakerSet) release(apiID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(apiID).release()
+}
+
+// remove drops the breaker of an API, so an API added again under the same
+// ID starts with a closed circuit
+func (s *breakerSet) remove(apiID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.breakers, apiID)
+}
+
+// statuses returns the state of every breaker, sorted by API ID
+func (s *breakerSet) statuses() []BreakerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(s.breakers))
+	for id, b := range s.breakers {
+		state := b.state
+		if state == BreakerOpen && b.now().Sub(b.openedAt) >= b.settings.openTimeout {
+			// The next request will probe the API
+			state = BreakerHalfOpen
+		}
+		statuses = append(statuses, BreakerStatus{
+			APIID:               id,
+			State:               state,
+			ConsecutiveFailures: b.consecutiveFailures,
+			Requests:            b.requests,
+			Failures:            b.failures,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].APIID < statuses[j].APIID
+	})
+	return statuses
+}
+
+// BreakerStatuses returns the circuit breaker state of every API that has
+// served traffic
+func (s *Server) BreakerStatuses() []BreakerStatus {
+	return s.breakers.statuses()
+}
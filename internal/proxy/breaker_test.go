@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// newTestBreakerSet creates a breaker set whose clock is advanced by the
+// returned function
+func newTestBreakerSet(settings config.Settings) (*breakerSet, func(time.Duration)) {
+	set := newBreakerSet(breakerSettingsFor(settings))
+	now := time.Now()
+	set.now = func() time.Time { return now }
+	return set, func(d time.Duration) { now = now.Add(d) }
+}
+
+// breakerState returns the reported state of an API's breaker
+func breakerState(set *breakerSet, apiID string) string {
+	for _, status := range set.statuses() {
+		if status.APIID == apiID {
+			return status.State
+		}
+	}
+	return ""
+}
+
+func TestBreakerSet_WithConsecutiveFailures_ShouldOpen(t *testing.T) {
+	// Arrange
+	set, _ := newTestBreakerSet(config.Settings{BreakerFailureThreshold: 3})
+
+	// Act
+	for i := 0; i < 3; i++ {
+		require.True(t, set.allow("api"))
+		set.recordFailure("api")
+	}
+
+	// Assert
+	assert.Equal(t, BreakerOpen, breakerState(set, "api"))
+	assert.False(t, set.allow("api"))
+}
+
+func TestBreakerSet_WithSuccessBetweenFailures_ShouldStayClosed(t *testing.T) {
+	// Arrange
+	set, _ := newTestBreakerSet(config.Settings{BreakerFailureThreshold: 3, BreakerMinRequests: 100})
+
+	// Act
+	set.recordFailure("api")
+	set.recordFailure("api")
+	set.recordSuccess("api")
+	set.recordFailure("api")
+
+	// Assert
+	assert.Equal(t, BreakerClosed, breakerState(set, "api"))
+	assert.True(t, set.allow("api"))
+}
+
+func TestBreakerSet_WithHighErrorRate_ShouldOpen(t *testing.T) {
+	// Arrange - failures never come three in a row
+	set, _ := newTestBreakerSet(config.Settings{
+		BreakerFailureThreshold: 3,
+		BreakerMinRequests:      6,
+		BreakerErrorRate:        0.5,
+	})
+
+	// Act
+	for i := 0; i < 3; i++ {
+		set.recordSuccess("api")
+		set.recordFailure("api")
+	}
+
+	// Assert
+	assert.Equal(t, BreakerOpen, breakerState(set, "api"))
+}
+
+func TestBreakerSet_WithExpiredWindow_ShouldForgetOldFailures(t *testing.T) {
+	// Arrange
+	set, advance := newTestBreakerSet(config.Settings{
+		BreakerFailureThreshold: 100,
+		BreakerMinRequests:      4,
+		BreakerWindow:           10,
+	})
+	set.recordFailure("api")
+	set.recordFailure("api")
+	set.recordSuccess("api")
+
+	// Act
+	advance(11 * time.Second)
+	set.recordFailure("api")
+
+	// Assert
+	assert.Equal(t, BreakerClosed, breakerState(set, "api"))
+}
+
+func TestBreakerSet_AfterOpenTimeout_ShouldLetOneProbeThrough(t *testing.T) {
+	// Arrange
+	set, advance := newTestBreakerSet(config.Settings{BreakerFailureThreshold: 1, BreakerOpenTimeout: 5})
+	set.recordFailure("api")
+	require.False(t, set.allow("api"))
+
+	// Act
+	advance(6 * time.Second)
+
+	// Assert - only a single probe is allowed while half-open
+	assert.Equal(t, BreakerHalfOpen, breakerState(set, "api"))
+	assert.True(t, set.allow("api"))
+	assert.False(t, set.allow("api"))
+
+	// Act & Assert - a successful probe closes the circuit
+	set.recordSuccess("api")
+	assert.Equal(t, BreakerClosed, breakerState(set, "api"))
+	assert.True(t, set.allow("api"))
+}
+
+func TestBreakerSet_WithFailedProbe_ShouldReopen(t *testing.T) {
+	// Arrange
+	set, advance := newTestBreakerSet(config.Settings{BreakerFailureThreshold: 1, BreakerOpenTimeout: 5})
+	set.recordFailure("api")
+	advance(6 * time.Second)
+	require.True(t, set.allow("api"))
+
+	// Act
+	set.recordFailure("api")
+
+	// Assert
+	assert.Equal(t, BreakerOpen, breakerState(set, "api"))
+	assert.False(t, set.allow("api"))
+}
+
+func TestServer_HandleRequest_WithOpenCircuit_ShouldFailFast(t *testing.T) {
+	// Arrange
+	var calls int64
+	target := newCountingTarget("target", http.StatusServiceUnavailable, &calls)
+	defer target.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "target", URL: target.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "target", BreakerFailureThreshold: 2},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act - two failures open the circuit
+	postThroughProxy(t, server, "a")
+	postThroughProxy(t, server, "b")
+	status, _ := postThroughProxy(t, server, "c")
+
+	// Assert - the third request never reaches the upstream
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&calls))
+
+	statuses := server.BreakerStatuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, BreakerOpen, statuses[0].State)
+}
+
+func TestServer_HandleRequest_WithOpenCircuit_ShouldUseFallback(t *testing.T) {
+	// Arrange
+	var primaryCalls, fallbackCalls int64
+	primary := newCountingTarget("primary", http.StatusOK, &primaryCalls)
+	defer primary.Close()
+	fallback := newCountingTarget("fallback", http.StatusOK, &fallbackCalls)
+	defer fallback.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "primary", URL: primary.URL},
+			{ID: "fallback", URL: fallback.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "primary", Fallback: []string{"fallback"}, BreakerFailureThreshold: 1},
+	}
+	server := NewServer(cfg)
+	server.breakers.recordFailure("primary")
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	status, body := postThroughProxy(t, server, "x")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "fallback:x", body)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&primaryCalls))
+}
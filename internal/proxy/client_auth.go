@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"octopus-cli/internal/config"
+)
+
+// clientKeyHeaders lists the headers agents send their API key in. Agents
+// keep sending their usual dummy key, which becomes the proxy token.
+var clientKeyHeaders = []string{"X-Api-Key", "Authorization", "X-Goog-Api-Key", "Api-Key"}
+
+// headerKey returns the key carried by the header name of r, without any
+// bearer prefix
+func headerKey(r *http.Request, name string) string {
+	value := strings.TrimSpace(r.Header.Get(name))
+	if name == "Authorization" && len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+		value = strings.TrimSpace(value[7:])
+	}
+	return value
+}
+
+// presentedToken returns the key a client sent and a function removing it
+// from every place of the request it appears in, so the proxy token never
+// reaches an upstream
+func presentedToken(r *http.Request) (string, func()) {
+	token := ""
+	for _, name := range clientKeyHeaders {
+		if token = headerKey(r, name); token != "" {
+			break
+		}
+	}
+	if token == "" {
+		token = r.URL.Query().Get("key")
+	}
+	if token == "" {
+		return "", func() {}
+	}
+
+	return token, func() {
+		for _, name := range clientKeyHeaders {
+			if headerKey(r, name) == token {
+				r.Header.Del(name)
+			}
+		}
+		query := r.URL.Query()
+		if query.Get("key") == token {
+			query.Del("key")
+			r.URL.RawQuery = query.Encode()
+		}
+	}
+}
+
+// clientTokenLabel returns the label of the token at index, naming
+// unlabeled tokens by position
+func clientTokenLabel(token config.ClientTokenConfig, index int) string {
+	if token.Label != "" {
+		return token.Label
+	}
+	return fmt.Sprintf("token-%d", index+1)
+}
+
+// authenticateClient checks the token r presents against tokens. On
+// success it removes the token from r and returns the token's label.
+// Every token is compared in constant time so timing reveals none of them.
+func authenticateClient(r *http.Request, tokens []config.ClientTokenConfig) (string, bool) {
+	presented, remove := presentedToken(r)
+	if presented == "" {
+		return "", false
+	}
+
+	match := -1
+	for i, token := range tokens {
+		if token.Token != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(token.Token)) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return "", false
+	}
+
+	remove()
+	return clientTokenLabel(tokens[match], match), true
+}
+
+// writeUnauthorized rejects a request with a 401 in the error format of the
+// protocol the client speaks
+func writeUnauthorized(w http.ResponseWriter, r *http.Request) {
+	const message = "invalid Octopus access token"
+
+	var body interface{}
+	protocol := inboundProtocol(r)
+	switch {
+	case protocol == ProtocolGemini || (protocol == "" && strings.Contains(r.URL.Path, "/v1beta/")):
+		var payload struct {
+			Error struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		payload.Error.Code = http.StatusUnauthorized
+		payload.Error.Message = message
+		payload.Error.Status = "UNAUTHENTICATED"
+		body = payload
+	case protocol == ProtocolOpenAI || (protocol == "" && r.Header.Get("X-Api-Key") == "" && r.Header.Get("Authorization") != ""):
+		var payload openAIErrorBody
+		code := "invalid_api_key"
+		payload.Error.Message = message
+		payload.Error.Type = openAIErrorType(http.StatusUnauthorized)
+		payload.Error.Code = &code
+		body = payload
+	default:
+		body = anthropicError(anthropicErrorType(http.StatusUnauthorized), message)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, message, http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write(data)
+}
+
+// clientCounter counts requests per client token label
+type clientCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newClientCounter() *clientCounter {
+	return &clientCounter{counts: map[string]int64{}}
+}
+
+func (c *clientCounter) add(label string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[label]++
+}
+
+// snapshot copies the counts, or returns nil when nothing was counted
+func (c *clientCounter) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.counts) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(c.counts))
+	for label, count := range c.counts {
+		counts[label] = count
+	}
+	return counts
+}
@@ -0,0 +1,207 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+var testClientTokens = []config.ClientTokenConfig{
+	{Token: "octo-laptop-secret", Label: "laptop"},
+	{Token: "octo-ci-secret"},
+}
+
+func TestServer_HandleRequest_WithUnknownClientToken_ShouldRejectInClientProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		value    string
+		expected string
+	}{
+		{
+			name:     "anthropic",
+			path:     "/v1/messages",
+			header:   "X-Api-Key",
+			value:    "sk-ant-dummy",
+			expected: `{"type":"error","error":{"type":"authentication_error","message":"invalid Octopus access token"}}`,
+		},
+		{
+			name:     "openai",
+			path:     "/v1/chat/completions",
+			header:   "Authorization",
+			value:    "Bearer sk-dummy",
+			expected: `{"error":{"message":"invalid Octopus access token","type":"authentication_error","param":null,"code":"invalid_api_key"}}`,
+		},
+		{
+			name:     "gemini",
+			path:     "/v1beta/models/gemini-2.5-pro:generateContent",
+			header:   "X-Goog-Api-Key",
+			value:    "AIza-dummy",
+			expected: `{"error":{"code":401,"message":"invalid Octopus access token","status":"UNAUTHENTICATED"}}`,
+		},
+		{
+			name:     "missing token",
+			path:     "/v1/messages",
+			expected: `{"type":"error","error":{"type":"authentication_error","message":"invalid Octopus access token"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var upstreamCalls int32
+			targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&upstreamCalls, 1)
+			}))
+			defer targetServer.Close()
+
+			cfg := &config.Config{
+				Server:   config.ServerConfig{Port: 0, ClientTokens: testClientTokens},
+				APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL, APIKey: "real-key"}},
+				Settings: config.Settings{ActiveAPI: "target"},
+			}
+			server := NewServer(cfg)
+			require.NoError(t, server.Start())
+			defer server.Stop()
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d%s", server.GetPort(), tt.path), strings.NewReader(`{}`))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			// Act
+			resp, err := http.DefaultClient.Do(req)
+
+			// Assert
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			assert.JSONEq(t, tt.expected, string(body))
+			assert.Zero(t, atomic.LoadInt32(&upstreamCalls))
+			assert.Equal(t, int64(1), server.GetStats().UnauthorizedCount)
+		})
+	}
+}
+
+func TestServer_HandleRequest_WithValidClientToken_ShouldStripTokenAndCountLabel(t *testing.T) {
+	// Arrange - the API has no key of its own, so nothing replaces the
+	// client's credentials upstream
+	type upstreamCall struct {
+		apiKey, authorization string
+	}
+	calls := make(chan upstreamCall, 2)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- upstreamCall{r.Header.Get("X-Api-Key"), r.Header.Get("Authorization")}
+		w.Write([]byte(`{}`))
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0, ClientTokens: testClientTokens},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	send := func(header, value string) int {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), strings.NewReader(`{}`))
+		req.Header.Set(header, value)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Act
+	laptopStatus := send("X-Api-Key", "octo-laptop-secret")
+	ciStatus := send("Authorization", "Bearer octo-ci-secret")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, laptopStatus)
+	assert.Equal(t, http.StatusOK, ciStatus)
+	for i := 0; i < 2; i++ {
+		call := <-calls
+		assert.Empty(t, call.apiKey)
+		assert.Empty(t, call.authorization)
+	}
+	assert.Equal(t, map[string]int64{"laptop": 1, "token-2": 1}, server.GetStats().Clients)
+}
+
+func TestPresentedToken_ShouldReadEveryKeyLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header string
+		value  string
+	}{
+		{"x-api-key", "/v1/messages", "X-Api-Key", "secret"},
+		{"bearer", "/v1/messages", "Authorization", "Bearer secret"},
+		{"google header", "/v1beta/models", "X-Goog-Api-Key", "secret"},
+		{"azure header", "/v1/chat/completions", "Api-Key", "secret"},
+		{"google query", "/v1beta/models?key=secret&alt=sse", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("POST", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			// Act
+			token, remove := presentedToken(req)
+			remove()
+
+			// Assert
+			assert.Equal(t, "secret", token)
+			assert.Empty(t, req.Header.Get(tt.header))
+			assert.NotContains(t, req.URL.RawQuery, "key=")
+		})
+	}
+}
+
+func TestPresentedToken_WithTokenInSeveralPlaces_ShouldRemoveEveryCopy(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/v1/messages?key=secret&alt=sse", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Api-Key", "other")
+
+	// Act
+	token, remove := presentedToken(req)
+	remove()
+
+	// Assert
+	assert.Equal(t, "secret", token)
+	assert.Empty(t, req.Header.Get("X-Api-Key"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Equal(t, "other", req.Header.Get("Api-Key"), "values other than the token are left alone")
+	assert.Equal(t, "alt=sse", req.URL.RawQuery)
+}
+
+func TestServer_Start_WithEmptyClientToken_ShouldReturnError(t *testing.T) {
+	// Arrange
+	server := NewServer(&config.Config{
+		Server: config.ServerConfig{Port: 0, ClientTokens: []config.ClientTokenConfig{{Label: "laptop"}}},
+	})
+
+	// Act
+	err := server.Start()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty token")
+}
@@ -48,6 +48,138 @@ func (cm *ConfigManager) GetActiveAPI() (*config.APIConfig, error) {
 	return nil, fmt.Errorf("active API '%s' not found", activeID)
 }
 
+// GetAPI returns a copy of the API configuration with the given ID
+func (cm *ConfigManager) GetAPI(apiID string) (*config.APIConfig, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for _, api := range cm.config.APIs {
+		if api.ID == apiID {
+			apiCopy := api
+			return &apiCopy, nil
+		}
+	}
+
+	return nil, fmt.Errorf("API not found: %s", apiID)
+}
+
+// GetFallbackAPIs returns the configured fallback APIs in order, skipping
+// IDs that no longer exist
+func (cm *ConfigManager) GetFallbackAPIs() []config.APIConfig {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	fallbacks := make([]config.APIConfig, 0, len(cm.config.Settings.Fallback))
+	for _, id := range cm.config.Settings.Fallback {
+		for _, api := range cm.config.APIs {
+			if api.ID == id {
+				fallbacks = append(fallbacks, api)
+				break
+			}
+		}
+	}
+	return fallbacks
+}
+
+// GetActiveGroup returns the members of the active group with their
+// weights, skipping IDs that no longer exist, and the balance strategy
+func (cm *ConfigManager) GetActiveGroup() ([]groupMember, string) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	members := make([]groupMember, 0, len(cm.config.Settings.ActiveGroup))
+	for _, member := range cm.config.Settings.ActiveGroup {
+		for _, api := range cm.config.APIs {
+			if api.ID == member.ID {
+				members = append(members, groupMember{api: api, weight: member.Weight})
+				break
+			}
+		}
+	}
+	return members, cm.config.Settings.BalanceStrategy
+}
+
+// GetAgentAPIID returns the ID of the API assigned to an agent, or an empty
+// string if the agent uses the active API or its API no longer exists
+func (cm *ConfigManager) GetAgentAPIID(agent string) string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	apiID := cm.config.Settings.AgentAPIs[agent]
+	if apiID == "" || !cm.hasAPI(apiID) {
+		return ""
+	}
+	return apiID
+}
+
+// SwitchAgentAPI assigns an API to a single agent
+func (cm *ConfigManager) SwitchAgentAPI(agent, apiID string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	found := false
+	for _, api := range cm.config.APIs {
+		if api.ID == apiID {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("API not found: %s", apiID)
+	}
+
+	// Replace rather than mutate the map so copies from GetConfig stay intact
+	agentAPIs := make(map[string]string, len(cm.config.Settings.AgentAPIs)+1)
+	for name, id := range cm.config.Settings.AgentAPIs {
+		agentAPIs[name] = id
+	}
+	agentAPIs[agent] = apiID
+	cm.config.Settings.AgentAPIs = agentAPIs
+	return nil
+}
+
+// GetRoutes returns a copy of the routing table, skipping routes to APIs
+// that no longer exist
+func (cm *ConfigManager) GetRoutes() []config.RouteConfig {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	routes := make([]config.RouteConfig, 0, len(cm.config.Routes))
+	for _, route := range cm.config.Routes {
+		if cm.hasAPI(route.API) {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// GetModelRoutes returns a copy of the model routing rules, skipping rules
+// for APIs that no longer exist
+func (cm *ConfigManager) GetModelRoutes() []config.ModelRouteConfig {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	routes := make([]config.ModelRouteConfig, 0, len(cm.config.ModelRoutes))
+	for _, route := range cm.config.ModelRoutes {
+		if cm.hasAPI(route.API) {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// hasAPI reports whether an API with the given ID exists. The caller must
+// hold cm.mu.
+func (cm *ConfigManager) hasAPI(apiID string) bool {
+	for _, api := range cm.config.APIs {
+		if api.ID == apiID {
+			return true
+		}
+	}
+	return false
+}
+
 // SwitchAPI switches to a different API configuration
 func (cm *ConfigManager) SwitchAPI(apiID string) error {
 	cm.mu.Lock()
@@ -86,6 +218,21 @@ func (cm *ConfigManager) AddAPI(apiConfig config.APIConfig) error {
 	return nil
 }
 
+// UpdateAPI replaces an existing API configuration with the same ID
+func (cm *ConfigManager) UpdateAPI(apiConfig config.APIConfig) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for i, api := range cm.config.APIs {
+		if api.ID == apiConfig.ID {
+			cm.config.APIs[i] = apiConfig
+			return nil
+		}
+	}
+
+	return fmt.Errorf("API with ID '%s' not found", apiConfig.ID)
+}
+
 // RemoveAPI removes an API configuration
 func (cm *ConfigManager) RemoveAPI(apiID string) error {
 	cm.mu.Lock()
@@ -112,6 +259,39 @@ func (cm *ConfigManager) RemoveAPI(apiID string) error {
 	}
 
 	cm.config.APIs = newAPIs
+
+	// Drop routes to it so matching requests use the active API, replacing
+	// the slices so copies from GetConfig stay intact
+	routes := make([]config.RouteConfig, 0, len(cm.config.Routes))
+	for _, route := range cm.config.Routes {
+		if route.API != apiID {
+			routes = append(routes, route)
+		}
+	}
+	cm.config.Routes = routes
+
+	modelRoutes := make([]config.ModelRouteConfig, 0, len(cm.config.ModelRoutes))
+	for _, route := range cm.config.ModelRoutes {
+		if route.API != apiID {
+			modelRoutes = append(modelRoutes, route)
+		}
+	}
+	cm.config.ModelRoutes = modelRoutes
+
+	// Agents assigned to it fall back to the active API
+	for _, id := range cm.config.Settings.AgentAPIs {
+		if id != apiID {
+			continue
+		}
+		agentAPIs := make(map[string]string, len(cm.config.Settings.AgentAPIs))
+		for agent, id := range cm.config.Settings.AgentAPIs {
+			if id != apiID {
+				agentAPIs[agent] = id
+			}
+		}
+		cm.config.Settings.AgentAPIs = agentAPIs
+		break
+	}
 	return nil
 }
 
@@ -161,5 +341,11 @@ func (cm *ConfigManager) GetConfig() *config.Config {
 	configCopy.APIs = make([]config.APIConfig, len(cm.config.APIs))
 	copy(configCopy.APIs, cm.config.APIs)
 
+	// Copy the routing table
+	configCopy.Routes = make([]config.RouteConfig, len(cm.config.Routes))
+	copy(configCopy.Routes, cm.config.Routes)
+	configCopy.ModelRoutes = make([]config.ModelRouteConfig, len(cm.config.ModelRoutes))
+	copy(configCopy.ModelRoutes, cm.config.ModelRoutes)
+
 	return &configCopy
 }
@@ -231,6 +231,62 @@ func TestConfigManager_RemoveAPI_WithActiveAPI_ShouldClearActiveAPI(t *testing.T
 	assert.Equal(t, "api2", apis[0].ID)
 }
 
+func TestConfigManager_RemoveAPI_ShouldDropRoutesToIt(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		APIs: []config.APIConfig{
+			{ID: "api1", Name: "API 1", URL: "https://api1.com", APIKey: "key1"},
+			{ID: "api2", Name: "API 2", URL: "https://api2.com", APIKey: "key2"},
+		},
+		Settings: config.Settings{ActiveAPI: "api1"},
+		Routes: []config.RouteConfig{
+			{Path: "/v1/messages", API: "api2"},
+			{Path: "/v1/models", API: "api1"},
+		},
+		ModelRoutes: []config.ModelRouteConfig{
+			{Model: "gpt-*", API: "api2"},
+			{Model: "claude-*", API: "api1"},
+		},
+	}
+	manager := NewConfigManager(cfg)
+	before := manager.GetConfig()
+
+	// Act
+	err := manager.RemoveAPI("api2")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []config.RouteConfig{{Path: "/v1/models", API: "api1"}}, manager.GetConfig().Routes)
+	assert.Equal(t, []config.ModelRouteConfig{{Model: "claude-*", API: "api1"}}, manager.GetConfig().ModelRoutes)
+	assert.Len(t, before.Routes, 2, "earlier copies are left intact")
+}
+
+func TestConfigManager_RemoveAPI_ShouldUnassignAgentsUsingIt(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		APIs: []config.APIConfig{
+			{ID: "api1", Name: "API 1", URL: "https://api1.com", APIKey: "key1"},
+			{ID: "api2", Name: "API 2", URL: "https://api2.com", APIKey: "key2"},
+		},
+		Settings: config.Settings{
+			ActiveAPI: "api1",
+			AgentAPIs: map[string]string{"claude": "api2", "codex": "api1"},
+		},
+	}
+	manager := NewConfigManager(cfg)
+	before := manager.GetConfig()
+
+	// Act
+	err := manager.RemoveAPI("api2")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, manager.GetAgentAPIID("claude"))
+	assert.Equal(t, "api1", manager.GetAgentAPIID("codex"))
+	assert.Equal(t, map[string]string{"codex": "api1"}, manager.GetConfig().Settings.AgentAPIs)
+	assert.Equal(t, "api2", before.Settings.AgentAPIs["claude"], "earlier copies are left intact")
+}
+
 func TestConfigManager_RemoveAPI_WithNonExistentAPI_ShouldReturnError(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// eventStreamMessage is a frame of the binary AWS event stream encoding,
+// which Bedrock uses for streamed responses. Only string headers are kept.
+type eventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// maxEventStreamMessage bounds the size of a single frame
+const maxEventStreamMessage = 16 << 20
+
+// errEventStreamChecksum reports a frame whose CRC does not match
+var errEventStreamChecksum = errors.New("event stream checksum mismatch")
+
+// readEventStreamMessage reads the next frame from r. It returns io.EOF
+// when r ends cleanly between frames.
+//
+// A frame is a 12-byte prelude (total length, headers length and their
+// CRC32), the headers, the payload and a CRC32 of everything before it.
+func readEventStreamMessage(r io.Reader) (eventStreamMessage, error) {
+	var prelude [12]byte
+	if _, err := io.ReadFull(r, prelude[:]); err != nil {
+		return eventStreamMessage{}, err
+	}
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:]) {
+		return eventStreamMessage{}, errEventStreamChecksum
+	}
+
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if total < 16 || total > maxEventStreamMessage || headersLen > total-16 {
+		return eventStreamMessage{}, fmt.Errorf("invalid event stream frame of %d bytes", total)
+	}
+
+	rest := make([]byte, total-12)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return eventStreamMessage{}, err
+	}
+	body, checksum := rest[:len(rest)-4], binary.BigEndian.Uint32(rest[len(rest)-4:])
+	crc := crc32.NewIEEE()
+	crc.Write(prelude[:])
+	crc.Write(body)
+	if crc.Sum32() != checksum {
+		return eventStreamMessage{}, errEventStreamChecksum
+	}
+
+	headers, err := parseEventStreamHeaders(body[:headersLen])
+	if err != nil {
+		return eventStreamMessage{}, err
+	}
+	return eventStreamMessage{Headers: headers, Payload: body[headersLen:]}, nil
+}
+
+// eventStreamValueSizes gives the size of the fixed-length header value
+// types, indexed by type
+var eventStreamValueSizes = map[byte]int{0: 0, 1: 0, 2: 1, 3: 2, 4: 4, 5: 8, 8: 8, 9: 16}
+
+// Header value types carrying a 2-byte length prefix
+const (
+	eventStreamBytes  = 6
+	eventStreamString = 7
+)
+
+func parseEventStreamHeaders(data []byte) (map[string]string, error) {
+	malformed := errors.New("malformed event stream headers")
+	headers := map[string]string{}
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+1 {
+			return nil, malformed
+		}
+		name := string(data[1 : 1+nameLen])
+		valueType := data[1+nameLen]
+		data = data[2+nameLen:]
+
+		switch valueType {
+		case eventStreamBytes, eventStreamString:
+			if len(data) < 2 {
+				return nil, malformed
+			}
+			valueLen := int(binary.BigEndian.Uint16(data))
+			if len(data) < 2+valueLen {
+				return nil, malformed
+			}
+			if valueType == eventStreamString {
+				headers[name] = string(data[2 : 2+valueLen])
+			}
+			data = data[2+valueLen:]
+		default:
+			size, ok := eventStreamValueSizes[valueType]
+			if !ok || len(data) < size {
+				return nil, malformed
+			}
+			data = data[size:]
+		}
+	}
+	return headers, nil
+}
@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// defaultFailoverCooldown is how long a failed API is skipped by default
+const defaultFailoverCooldown = 60 * time.Second
+
+// failoverTracker remembers which APIs failed recently so requests go
+// straight to the next API in the chain until the cooldown expires
+type failoverTracker struct {
+	mu       sync.Mutex
+	failedAt map[string]time.Time
+	now      func() time.Time
+}
+
+// newFailoverTracker creates an empty failover tracker
+func newFailoverTracker() *failoverTracker {
+	return &failoverTracker{
+		failedAt: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// markFailed records a failure of the given API
+func (t *failoverTracker) markFailed(apiID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failedAt[apiID] = t.now()
+}
+
+// markHealthy clears the failure record of the given API
+func (t *failoverTracker) markHealthy(apiID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failedAt, apiID)
+}
+
+// coolingDown reports whether the API failed within the cooldown period
+func (t *failoverTracker) coolingDown(apiID string, cooldown time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failedAt, ok := t.failedAt[apiID]
+	if !ok {
+		return false
+	}
+	if t.now().Sub(failedAt) >= cooldown {
+		delete(t.failedAt, apiID)
+		return false
+	}
+	return true
+}
+
+// order arranges the chain so APIs that are cooling down are tried last,
+// keeping the configured order otherwise
+func (t *failoverTracker) order(chain []config.APIConfig, cooldown time.Duration) []config.APIConfig {
+	ordered := make([]config.APIConfig, 0, len(chain))
+	var cooling []config.APIConfig
+	for _, api := range chain {
+		if t.coolingDown(api.ID, cooldown) {
+			cooling = append(cooling, api)
+		} else {
+			ordered = append(ordered, api)
+		}
+	}
+	return append(ordered, cooling...)
+}
+
+// isFailoverStatus reports whether an upstream status should move the
+// request on to the next API in the chain
+func isFailoverStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// failoverChain returns the primary API followed by the configured
+// fallbacks, without duplicates
+func (s *Server) failoverChain(primary *config.APIConfig) []config.APIConfig {
+	chain := []config.APIConfig{*primary}
+	seen := map[string]bool{primary.ID: true}
+
+	for _, api := range s.configManager.GetFallbackAPIs() {
+		if !seen[api.ID] {
+			seen[api.ID] = true
+			chain = append(chain, api)
+		}
+	}
+
+	if len(chain) == 1 {
+		return chain
+	}
+	return s.failover.order(chain, s.failoverCooldown())
+}
+
+// failoverCooldown returns the configured failover cooldown
+func (s *Server) failoverCooldown() time.Duration {
+	if seconds := s.config.Settings.FailoverCooldown; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultFailoverCooldown
+}
+
+// forwardWithFailover tries each API of the chain in turn until one of them
+// produces a usable response, then relays that response to the client.
+// APIs with an open circuit are skipped without being contacted.
+func (s *Server) forwardWithFailover(w http.ResponseWriter, r *http.Request, body *requestBody, chain []config.APIConfig) error {
+	// lastResp keeps the latest failing response so it can still be
+	// relayed if no later API is able to do better
+	var lastResp *http.Response
+	var lastErr error
+	attempts := 0
+	model := chainModel(chain, body)
+	for i := range chain {
+		api := &chain[i]
+		if attempts > 0 && !body.replayable() {
+			// An oversized body has been consumed by the first attempt
+			break
+		}
+		apiBody, sentModel := s.mapRequestModel(api, body, model)
+		var apiReq *http.Request
+		var tr translator
+		provider, err := ProviderFor(api)
+		if err == nil {
+			apiReq, apiBody, tr, err = s.translateRequest(r, apiBody, api)
+		}
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Warn("Skipping API '%s': %v", api.ID, err)
+			}
+			if lastErr == nil && lastResp == nil {
+				lastErr = err
+			}
+			continue
+		}
+		if !s.breakers.allow(api.ID) {
+			if s.logger != nil {
+				s.logger.Warn("Circuit open for API '%s', skipping it", api.ID)
+			}
+			if lastErr == nil && lastResp == nil {
+				lastErr = fmt.Errorf("%w for API '%s'", errCircuitOpen, api.ID)
+			}
+			continue
+		}
+		release, settle, err := s.admit(r.Context(), api, apiBody)
+		if err != nil {
+			s.breakers.release(api.ID)
+			if r.Context().Err() != nil {
+				closeResponse(lastResp)
+				return fmt.Errorf("request to target failed: %w", err)
+			}
+			if s.logger != nil {
+				s.logger.Warn("API '%s' is over its rate limits, skipping it: %v", api.ID, err)
+			}
+			if lastErr == nil && lastResp == nil {
+				lastErr = fmt.Errorf("API '%s': %w", api.ID, err)
+			}
+			continue
+		}
+		attempts++
+
+		track := s.upstreams.begin(api.ID)
+		done := func(latency time.Duration, failed bool) {
+			track(latency, failed)
+			release()
+		}
+		start := time.Now()
+		engine := s.newForwardEngine(api)
+		engine.SetRetryGate(s.retryGate(api))
+		resp, err := engine.forward(r.Context(), apiReq, apiBody)
+		if err != nil {
+			done(0, true)
+			if r.Context().Err() != nil {
+				s.breakers.release(api.ID)
+				closeResponse(lastResp)
+				return fmt.Errorf("request to target failed: %w", err)
+			}
+
+			s.failover.markFailed(api.ID)
+			s.breakers.recordFailure(api.ID)
+			if lastResp == nil {
+				lastErr = fmt.Errorf("request to target failed: %w", err)
+			}
+			if s.logger != nil {
+				s.logger.Warn("API '%s' failed: %v", api.ID, err)
+			}
+			continue
+		}
+
+		latency := time.Since(start)
+
+		// Inspect the upstream's own response before it is translated
+		var errDetail string
+		if s.logger != nil && isFailoverStatus(resp.StatusCode) {
+			errDetail = peekErrorMessage(resp, provider)
+		}
+		recordUsage(resp, provider, func(usage TokenUsage) {
+			s.upstreams.addUsage(api.ID, usage)
+			settle(usage.InputTokens)
+		})
+
+		if tr != nil {
+			if err := tr.translateResponse(resp); err != nil {
+				done(latency, true)
+				// Only an upstream error counts against the API; a
+				// response it delivered that the proxy cannot translate
+				// says nothing about its health
+				if isFailoverStatus(resp.StatusCode) {
+					s.failover.markFailed(api.ID)
+					s.breakers.recordFailure(api.ID)
+				} else {
+					s.breakers.release(api.ID)
+				}
+				closeResponse(resp)
+				if lastResp == nil {
+					lastErr = err
+				}
+				if s.logger != nil {
+					s.logger.Warn("API '%s' sent a response that cannot be translated: %v", api.ID, err)
+				}
+				continue
+			}
+		}
+
+		if isFailoverStatus(resp.StatusCode) {
+			done(latency, true)
+			s.failover.markFailed(api.ID)
+			s.breakers.recordFailure(api.ID)
+			if s.logger != nil {
+				s.logger.Warn("API '%s' returned %d: %s", api.ID, resp.StatusCode, errDetail)
+			}
+			closeResponse(lastResp)
+			lastResp, lastErr = resp, nil
+			continue
+		}
+
+		s.failover.markHealthy(api.ID)
+		s.breakers.recordSuccess(api.ID)
+		closeResponse(lastResp)
+
+		if s.logger != nil {
+			if i > 0 {
+				s.logger.Info("Request served by fallback API '%s' after %d skipped or failed API(s)", api.ID, i)
+			} else {
+				s.logger.Info("Request served by API '%s'", api.ID)
+			}
+		}
+
+		if api.RewriteResponseModel && sentModel != model {
+			if err := rewriteResponseModel(resp, sentModel, model); err != nil {
+				done(latency, true)
+				closeResponse(resp)
+				return err
+			}
+		}
+
+		err = s.relayResponse(w, resp)
+		done(latency, false)
+		return err
+	}
+
+	if lastResp != nil {
+		return s.relayResponse(w, lastResp)
+	}
+	return lastErr
+}
+
+// chainModel returns the model named in the request body when any API of
+// the chain maps model names, so bodies are only parsed when needed
+func chainModel(chain []config.APIConfig, body *requestBody) string {
+	for i := range chain {
+		if len(chain[i].ModelMap) > 0 {
+			return requestModel(body)
+		}
+	}
+	return ""
+}
+
+// closeResponse closes a held response, if any
+func closeResponse(resp *http.Response) {
+	if resp != nil {
+		resp.Body.Close()
+	}
+}
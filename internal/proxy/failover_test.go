@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// newCountingTarget creates a target server that counts calls and responds
+// with the given status and its name
+func newCountingTarget(name string, status int, calls *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "%s:%s", name, body)
+	}))
+}
+
+// postThroughProxy sends a POST through the proxy and returns status and body
+func postThroughProxy(t *testing.T, server *Server, body string) (int, string) {
+	proxyURL := fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort())
+	resp, err := http.Post(proxyURL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestServer_Failover_WithFailingPrimary_ShouldServeFromFallback(t *testing.T) {
+	// Arrange
+	var primaryCalls, fallbackCalls int64
+	primary := newCountingTarget("primary", http.StatusServiceUnavailable, &primaryCalls)
+	defer primary.Close()
+	fallback := newCountingTarget("fallback", http.StatusOK, &fallbackCalls)
+	defer fallback.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "primary", URL: primary.URL},
+			{ID: "fallback", URL: fallback.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "primary", Fallback: []string{"fallback"}},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	status, body := postThroughProxy(t, server, `{"n":1}`)
+
+	// Assert - the same body is replayed against the fallback
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `fallback:{"n":1}`, body)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&primaryCalls))
+
+	// Act - the primary is skipped while cooling down
+	status, _ = postThroughProxy(t, server, `{"n":2}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&primaryCalls))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&fallbackCalls))
+}
+
+func TestServer_Failover_AfterCooldown_ShouldReturnToPrimary(t *testing.T) {
+	// Arrange
+	var primaryCalls, fallbackCalls int64
+	primary := newCountingTarget("primary", http.StatusOK, &primaryCalls)
+	defer primary.Close()
+	fallback := newCountingTarget("fallback", http.StatusOK, &fallbackCalls)
+	defer fallback.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "primary", URL: primary.URL},
+			{ID: "fallback", URL: fallback.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "primary", Fallback: []string{"fallback"}, FailoverCooldown: 30},
+	}
+	server := NewServer(cfg)
+	start := time.Now()
+	var elapsed int64
+	server.failover.now = func() time.Time {
+		return start.Add(time.Duration(atomic.LoadInt64(&elapsed)))
+	}
+	server.failover.markFailed("primary")
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act & Assert - still cooling down
+	_, body := postThroughProxy(t, server, "a")
+	assert.Equal(t, "fallback:a", body)
+
+	// Act & Assert - cooldown elapsed
+	atomic.StoreInt64(&elapsed, int64(31*time.Second))
+	_, body = postThroughProxy(t, server, "b")
+	assert.Equal(t, "primary:b", body)
+}
+
+func TestServer_Failover_WithRateLimitedChain_ShouldReturnLastResponse(t *testing.T) {
+	// Arrange - every API is rate limited
+	var calls1, calls2 int64
+	api1 := newCountingTarget("api1", http.StatusTooManyRequests, &calls1)
+	defer api1.Close()
+	api2 := newCountingTarget("api2", http.StatusTooManyRequests, &calls2)
+	defer api2.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "api1", URL: api1.URL},
+			{ID: "api2", URL: api2.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "api1", Fallback: []string{"api2"}},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	status, body := postThroughProxy(t, server, "x")
+
+	// Assert - the last upstream's own answer reaches the client
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, "api2:x", body)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls1))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls2))
+}
+
+func TestServer_Failover_WithConnectionError_ShouldUseFallback(t *testing.T) {
+	// Arrange
+	var fallbackCalls int64
+	fallback := newCountingTarget("fallback", http.StatusOK, &fallbackCalls)
+	defer fallback.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "down", URL: "http://127.0.0.1:1"},
+			{ID: "fallback", URL: fallback.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "down", Fallback: []string{"down", "fallback", "missing"}},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	status, body := postThroughProxy(t, server, "y")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "fallback:y", body)
+}
+
+func TestFailoverTracker_Order_ShouldMoveCoolingAPIsToTheEnd(t *testing.T) {
+	// Arrange
+	tracker := newFailoverTracker()
+	chain := []config.APIConfig{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	tracker.markFailed("a")
+
+	// Act
+	ordered := tracker.order(chain, time.Minute)
+
+	// Assert
+	ids := []string{ordered[0].ID, ordered[1].ID, ordered[2].ID}
+	assert.Equal(t, []string{"b", "c", "a"}, ids)
+
+	// Act & Assert - a healthy response clears the record
+	tracker.markHealthy("a")
+	assert.False(t, tracker.coolingDown("a", time.Minute))
+}
+
+func TestServer_Failover_WithUntranslatableResponse_ShouldNotOpenCircuit(t *testing.T) {
+	// Arrange - the primary answers 200 with a body that is not an OpenAI
+	// response, so the proxy cannot translate it
+	var primaryCalls, fallbackCalls int64
+	primary := newCountingTarget("primary", http.StatusOK, &primaryCalls)
+	defer primary.Close()
+	fallback := newCountingTarget("fallback", http.StatusOK, &fallbackCalls)
+	defer fallback.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "primary", URL: primary.URL, Protocol: ProtocolOpenAI},
+			{ID: "fallback", URL: fallback.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "primary", Fallback: []string{"fallback"}, BreakerFailureThreshold: 1},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+	request := `{"model":"claude-3","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`
+
+	// Act
+	firstStatus, firstBody := postThroughProxy(t, server, request)
+	secondStatus, _ := postThroughProxy(t, server, request)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, firstStatus)
+	assert.Contains(t, firstBody, "fallback:")
+	assert.Equal(t, http.StatusOK, secondStatus)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&primaryCalls), "the primary is neither cooled down nor cut off")
+	for _, status := range server.BreakerStatuses() {
+		assert.Equal(t, BreakerClosed, status.State, status.APIID)
+	}
+}
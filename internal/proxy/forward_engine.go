@@ -1,10 +1,14 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,52 +24,131 @@ type ForwardEngineStats struct {
 	StartTime          time.Time
 }
 
+// defaultReplayLimit is the largest request body buffered for retries
+const defaultReplayLimit = 32 << 20
+
 // ForwardEngine handles API request forwarding with retry logic
 type ForwardEngine struct {
-	apiConfig      *config.APIConfig
-	client         *http.Client
-	timeout        time.Duration
-	retryCount     int
-	totalRequests  int64
-	successfulReqs int64
-	failedReqs     int64
-	totalRetries   int64
-	startTime      time.Time
+	apiConfig        *config.APIConfig
+	client           *http.Client
+	clientOnce       sync.Once
+	timeouts         upstreamTimeouts
+	retryCount       int
+	replayLimit      int64
+	forwardedHeaders bool
+	retryGate        func(ctx context.Context) bool
+	totalRequests    int64
+	successfulReqs   int64
+	failedReqs       int64
+	totalRetries     int64
+	startTime        time.Time
 }
 
 // NewForwardEngine creates a new forward engine
 func NewForwardEngine(apiConfig *config.APIConfig) *ForwardEngine {
-	timeout := time.Duration(apiConfig.Timeout) * time.Second
-	if timeout <= 0 {
-		timeout = 30 * time.Second // default timeout
+	return &ForwardEngine{
+		apiConfig:   apiConfig,
+		timeouts:    timeoutsFor(apiConfig),
+		retryCount:  apiConfig.RetryCount,
+		replayLimit: defaultReplayLimit,
+		client:      &http.Client{},
+		startTime:   time.Now(),
 	}
+}
 
-	return &ForwardEngine{
-		apiConfig:  apiConfig,
-		timeout:    timeout,
-		retryCount: apiConfig.RetryCount,
-		client: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				Proxy: nil, // Disable proxy to avoid interference
-			},
-		},
-		startTime: time.Now(),
+// SetTransport makes the engine send requests through a shared transport,
+// so connections are reused across engines
+func (f *ForwardEngine) SetTransport(transport http.RoundTripper) {
+	f.client.Transport = transport
+}
+
+// httpClient returns the client requests are sent with, building a
+// transport of its own on first use unless SetTransport provided one
+func (f *ForwardEngine) httpClient() *http.Client {
+	f.clientOnce.Do(func() {
+		if f.client.Transport == nil {
+			f.client.Transport = newUpstreamTransport(f.timeouts, poolSettingsFor(config.Settings{}))
+		}
+	})
+	return f.client
+}
+
+// SetForwardedHeaders controls whether X-Forwarded-* and Via headers are
+// added to upstream requests
+func (f *ForwardEngine) SetForwardedHeaders(enabled bool) {
+	f.forwardedHeaders = enabled
+}
+
+// SetReplayLimit sets the largest request body, in bytes, that is buffered
+// so it can be replayed on retries. Larger bodies are sent only once.
+func (f *ForwardEngine) SetReplayLimit(limit int64) {
+	if limit > 0 {
+		f.replayLimit = limit
 	}
 }
 
-// ForwardRequest forwards a request to the target API with retry logic
+// SetRetryGate makes the engine ask gate before each retry. A retry the gate
+// refuses is not sent and the last outcome is returned instead.
+func (f *ForwardEngine) SetRetryGate(gate func(ctx context.Context) bool) {
+	f.retryGate = gate
+}
+
+// mayRetry reports whether another attempt may be sent
+func (f *ForwardEngine) mayRetry(ctx context.Context) bool {
+	return f.retryGate == nil || f.retryGate(ctx)
+}
+
+// ForwardRequest forwards a request to the target API with retry logic.
+// Retries only happen before a response is handed back, so nothing has
+// been written to the client yet when a request is replayed.
 func (f *ForwardEngine) ForwardRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
+	// Buffer the body so every attempt sends the same bytes
+	body, err := bufferRequestBody(req.Body, f.replayLimit)
+	if err != nil {
+		atomic.AddInt64(&f.totalRequests, 1)
+		atomic.AddInt64(&f.failedReqs, 1)
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	return f.forward(ctx, req, body)
+}
+
+// targetURL builds the upstream URL of a request from the base URL of the
+// API's provider
+func (f *ForwardEngine) targetURL(in *url.URL) (*url.URL, error) {
+	provider, err := ProviderFor(f.apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	base, err := provider.BaseURL(f.apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	return joinURL(base, in)
+}
+
+// forward sends req with an already buffered body, retrying as configured
+func (f *ForwardEngine) forward(ctx context.Context, req *http.Request, body *requestBody) (*http.Response, error) {
 	atomic.AddInt64(&f.totalRequests, 1)
 
-	// Create target URL
-	targetURL := f.apiConfig.URL + req.URL.Path
-	if req.URL.RawQuery != "" {
-		targetURL += "?" + req.URL.RawQuery
+	// Create target URL, keeping any base path of the API URL
+	targetURL, err := f.targetURL(req.URL)
+	if err != nil {
+		atomic.AddInt64(&f.failedReqs, 1)
+		return nil, err
+	}
+
+	attempts := f.retryCount
+	if attempts < 1 {
+		attempts = 1
+	}
+	if !body.replayable() {
+		// The body can only be streamed once
+		attempts = 1
 	}
 
 	var lastErr error
-	for attempt := 0; attempt < f.retryCount; attempt++ {
+	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
 			atomic.AddInt64(&f.totalRetries, 1)
 			// Add exponential backoff delay
@@ -78,44 +161,59 @@ func (f *ForwardEngine) ForwardRequest(ctx context.Context, req *http.Request) (
 			}
 		}
 
-		// Create new request for this attempt
-		targetReq, err := http.NewRequestWithContext(ctx, req.Method, targetURL, req.Body)
+		// Create new request for this attempt; its context is released
+		// when the response body is closed or goes idle
+		attemptCtx, cancel := context.WithCancel(ctx)
+		targetReq, err := http.NewRequestWithContext(attemptCtx, req.Method, targetURL.String(), body.reader())
 		if err != nil {
+			cancel()
 			lastErr = err
 			continue
 		}
+		if !body.replayable() {
+			targetReq.ContentLength = req.ContentLength
+		}
 
-		// Copy headers from original request
-		for name, values := range req.Header {
-			for _, value := range values {
-				targetReq.Header.Add(name, value)
-			}
+		// Copy end-to-end headers from original request
+		copyRequestHeaders(targetReq.Header, req.Header)
+		if f.forwardedHeaders {
+			addForwardedHeaders(targetReq.Header, req)
 		}
 
-		// Add API key if present
-		if f.apiConfig.APIKey != "" {
-			targetReq.Header.Set("Authorization", "Bearer "+f.apiConfig.APIKey)
+		// Inject the API key the way the upstream expects it
+		if err := applyAuth(targetReq, f.apiConfig); err != nil {
+			cancel()
+			lastErr = err
+			break
 		}
 
 		// Make the request
-		resp, err := f.client.Do(targetReq)
+		resp, err := f.httpClient().Do(targetReq)
 		if err != nil {
+			cancel()
 			lastErr = err
-			if f.shouldRetry(0, err) {
+			if ctx.Err() == nil && f.shouldRetry(0, err) && attempt < attempts-1 && f.mayRetry(ctx) {
 				continue
 			}
 			break
 		}
 
-		// Check if we should retry based on status code
+		// Retry on a retryable status while attempts are left. The last
+		// response is handed back as is, so the client still sees the
+		// upstream's status and error body.
 		if f.shouldRetry(resp.StatusCode, nil) {
-			resp.Body.Close()
-			lastErr = fmt.Errorf("received retryable status code: %d", resp.StatusCode)
-			continue
+			if attempt < attempts-1 && f.mayRetry(ctx) {
+				resp.Body.Close()
+				cancel()
+				lastErr = fmt.Errorf("received retryable status code: %d", resp.StatusCode)
+				continue
+			}
+			atomic.AddInt64(&f.failedReqs, 1)
+		} else {
+			atomic.AddInt64(&f.successfulReqs, 1)
 		}
 
-		// Success
-		atomic.AddInt64(&f.successfulReqs, 1)
+		resp.Body = withIdleTimeout(resp.Body, f.timeouts.idle, cancel)
 		return resp, nil
 	}
 
@@ -124,6 +222,46 @@ func (f *ForwardEngine) ForwardRequest(ctx context.Context, req *http.Request) (
 	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
+// requestBody holds a request body buffered for replay. When the body is
+// larger than the replay limit, rest holds the unread remainder.
+type requestBody struct {
+	data []byte
+	rest io.Reader
+}
+
+// bufferRequestBody reads up to limit bytes of body into memory
+func bufferRequestBody(body io.ReadCloser, limit int64) (*requestBody, error) {
+	if body == nil || body == http.NoBody {
+		return &requestBody{}, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return &requestBody{data: data, rest: body}, nil
+	}
+	return &requestBody{data: data}, nil
+}
+
+// replayable reports whether the whole body fits in memory
+func (b *requestBody) replayable() bool {
+	return b.rest == nil
+}
+
+// reader returns a fresh reader over the body for a new attempt
+func (b *requestBody) reader() io.Reader {
+	if !b.replayable() {
+		return io.MultiReader(bytes.NewReader(b.data), b.rest)
+	}
+	if len(b.data) == 0 {
+		return nil
+	}
+	return bytes.NewReader(b.data)
+}
+
 // shouldRetry determines if a request should be retried based on status code or error
 func (f *ForwardEngine) shouldRetry(statusCode int, err error) bool {
 	// Retry on network errors
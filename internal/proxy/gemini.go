@@ -0,0 +1,562 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// geminiRequest is a Gemini generateContent request
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// geminiContent is a turn of a conversation, with role "user" or "model"
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart holds exactly one kind of content
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string      `json:"name"`
+	Response interface{} `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	TopK            *int     `json:"topK,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+}
+
+// geminiResponse is a generateContent response, or one chunk of a stream
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *geminiUsage     `json:"usageMetadata"`
+	ModelVersion  string           `json:"modelVersion"`
+	ResponseID    string           `json:"responseId"`
+	Error         *json.RawMessage `json:"error"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+// anthropicUsage converts token counts. Thinking tokens are billed as
+// output, and cached prompt tokens are reported the way Anthropic does.
+func (u *geminiUsage) anthropicUsage() anthropicUsage {
+	return anthropicUsage{
+		InputTokens:          u.PromptTokenCount - u.CachedContentTokenCount,
+		OutputTokens:         u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		CacheReadInputTokens: u.CachedContentTokenCount,
+	}
+}
+
+// geminiStopReason maps a Gemini finishReason to an Anthropic stop_reason
+func geminiStopReason(finishReason string, calledTools bool) string {
+	switch finishReason {
+	case "MAX_TOKENS":
+		return "max_tokens"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "refusal"
+	}
+	if calledTools {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+// geminiUnsupportedSchemaKeys are JSON Schema keywords Gemini rejects in
+// function parameters
+var geminiUnsupportedSchemaKeys = []string{
+	"$schema",
+	"$id",
+	"additionalProperties",
+	"propertyNames",
+	"exclusiveMinimum",
+	"exclusiveMaximum",
+	"examples",
+	"default",
+}
+
+// geminiSchema strips keywords Gemini does not accept from a JSON Schema,
+// recursing into nested schemas but leaving property names alone
+func geminiSchema(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+
+	var schema map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		// Arrays of schemas, as found under anyOf or items
+		var list []json.RawMessage
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return raw
+		}
+		for i := range list {
+			list[i] = geminiSchema(list[i])
+		}
+		if data, err := json.Marshal(list); err == nil {
+			return data
+		}
+		return raw
+	}
+
+	for _, key := range geminiUnsupportedSchemaKeys {
+		delete(schema, key)
+	}
+	for key, value := range schema {
+		switch key {
+		case "properties", "$defs", "definitions":
+			var properties map[string]json.RawMessage
+			if err := json.Unmarshal(value, &properties); err == nil {
+				for name := range properties {
+					properties[name] = geminiSchema(properties[name])
+				}
+				if data, err := json.Marshal(properties); err == nil {
+					schema[key] = data
+				}
+			}
+		case "items", "anyOf", "oneOf", "allOf", "not":
+			schema[key] = geminiSchema(value)
+		}
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+// randomID returns prefix followed by random hex digits
+func randomID(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return prefix + "0"
+	}
+	return prefix + hex.EncodeToString(buf)
+}
+
+// newToolUseID returns an ID for a tool call that came without one. It is
+// a variable so tests can make IDs predictable.
+var newToolUseID = func() string {
+	return randomID("toolu_")
+}
+
+// anthropicToGemini lets Anthropic clients such as Claude Code talk to the
+// Gemini API. The model moves from the body to the request path.
+type anthropicToGemini struct {
+	model string
+}
+
+func (t *anthropicToGemini) translateRequest(r *http.Request, body []byte) (*http.Request, []byte, error) {
+	var in anthropicRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, nil, fmt.Errorf("invalid Anthropic request: %w", err)
+	}
+	if in.Model == "" {
+		return nil, nil, fmt.Errorf("request names no model")
+	}
+	t.model = in.Model
+
+	out := geminiRequest{Contents: []geminiContent{}}
+
+	system, err := anthropicText(in.System)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid system prompt: %w", err)
+	}
+	if system != "" {
+		out.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+
+	// Gemini matches function responses to calls by name, which tool
+	// results only reference through the tool_use ID
+	toolNames := map[string]string{}
+	for _, message := range in.Messages {
+		content, err := geminiContentFor(message, toolNames)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(content.Parts) > 0 {
+			out.Contents = append(out.Contents, content)
+		}
+	}
+
+	if len(in.Tools) > 0 {
+		declarations := geminiTool{}
+		for _, tool := range in.Tools {
+			declarations.FunctionDeclarations = append(declarations.FunctionDeclarations, geminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  geminiSchema(tool.InputSchema),
+			})
+		}
+		out.Tools = []geminiTool{declarations}
+	}
+
+	if choice := in.ToolChoice; choice != nil {
+		calling := geminiFunctionCallingConfig{}
+		switch choice.Type {
+		case "auto":
+			calling.Mode = "AUTO"
+		case "any":
+			calling.Mode = "ANY"
+		case "none":
+			calling.Mode = "NONE"
+		case "tool":
+			calling.Mode = "ANY"
+			calling.AllowedFunctionNames = []string{choice.Name}
+		}
+		if calling.Mode != "" {
+			out.ToolConfig = &geminiToolConfig{FunctionCallingConfig: calling}
+		}
+	}
+
+	if in.MaxTokens > 0 || in.Temperature != nil || in.TopP != nil || in.TopK != nil || len(in.StopSequences) > 0 {
+		out.GenerationConfig = &geminiGenerationConfig{
+			MaxOutputTokens: in.MaxTokens,
+			Temperature:     in.Temperature,
+			TopP:            in.TopP,
+			TopK:            in.TopK,
+			StopSequences:   in.StopSequences,
+		}
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method := ":generateContent"
+	if in.Stream {
+		method = ":streamGenerateContent"
+	}
+	translated := withTranslatedPath(r, "/v1/messages", "/v1beta/models/"+url.PathEscape(in.Model)+method)
+	if in.Stream {
+		query := translated.URL.Query()
+		query.Set("alt", "sse")
+		translated.URL.RawQuery = query.Encode()
+	}
+	return translated, data, nil
+}
+
+// geminiContentFor converts one Anthropic message, recording the names of
+// tool calls so later tool results can refer to them
+func geminiContentFor(message anthropicMessage, toolNames map[string]string) (geminiContent, error) {
+	blocks, err := anthropicBlocks(message.Content)
+	if err != nil {
+		return geminiContent{}, err
+	}
+
+	content := geminiContent{Role: "user", Parts: []geminiPart{}}
+	if message.Role == "assistant" {
+		content.Role = "model"
+	}
+
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				content.Parts = append(content.Parts, geminiPart{Text: block.Text})
+			}
+		case "image":
+			if part, ok := geminiImage(block.Source); ok {
+				content.Parts = append(content.Parts, part)
+			}
+		case "tool_use":
+			toolNames[block.ID] = block.Name
+			args := block.Input
+			if len(args) == 0 {
+				args = json.RawMessage(`{}`)
+			}
+			content.Parts = append(content.Parts, geminiPart{
+				FunctionCall: &geminiFunctionCall{Name: block.Name, Args: args},
+			})
+		case "tool_result":
+			text, err := anthropicText(block.Content)
+			if err != nil {
+				return geminiContent{}, fmt.Errorf("invalid tool result: %w", err)
+			}
+			response := map[string]string{"content": text}
+			if block.IsError {
+				response = map[string]string{"error": text}
+			}
+			content.Parts = append(content.Parts, geminiPart{
+				FunctionResponse: &geminiFunctionResponse{Name: toolNames[block.ToolUseID], Response: response},
+			})
+		}
+	}
+	return content, nil
+}
+
+// geminiImage converts an image source to inline data or a file reference
+func geminiImage(source *anthropicImageSource) (geminiPart, bool) {
+	switch {
+	case source == nil:
+		return geminiPart{}, false
+	case source.Type == "base64":
+		return geminiPart{InlineData: &geminiBlob{MimeType: source.MediaType, Data: source.Data}}, true
+	case source.Type == "url":
+		return geminiPart{FileData: &geminiFileData{MimeType: source.MediaType, FileURI: source.URL}}, true
+	}
+	return geminiPart{}, false
+}
+
+func (t *anthropicToGemini) translateResponse(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		data, err := readResponseBody(resp)
+		if err != nil {
+			return err
+		}
+		converted, err := json.Marshal(anthropicError(anthropicErrorType(resp.StatusCode), upstreamErrorMessage(data, resp.StatusCode)))
+		if err != nil {
+			return err
+		}
+		replaceJSONBody(resp, converted)
+		return nil
+	}
+
+	if isEventStream(resp) {
+		stream := &geminiStream{events: anthropicEvents{model: t.model}}
+		resp.Body = newEventStreamConverter(resp.Body, stream.convert, stream.finish)
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+		return nil
+	}
+
+	data, err := readResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	var in geminiResponse
+	if err := json.Unmarshal(data, &in); err != nil {
+		return fmt.Errorf("invalid Gemini response: %w", err)
+	}
+
+	out := anthropicResponse{
+		ID:      geminiMessageID(in.ResponseID),
+		Type:    "message",
+		Role:    "assistant",
+		Model:   t.model,
+		Content: []interface{}{},
+	}
+	calledTools := false
+	finishReason := ""
+	if len(in.Candidates) > 0 {
+		candidate := in.Candidates[0]
+		finishReason = candidate.FinishReason
+
+		var text strings.Builder
+		flushText := func() {
+			if text.Len() > 0 {
+				out.Content = append(out.Content, anthropicTextBlock{Type: "text", Text: text.String()})
+				text.Reset()
+			}
+		}
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+				// Thought summaries carry no signature Anthropic clients could replay
+			case part.FunctionCall != nil:
+				flushText()
+				calledTools = true
+				out.Content = append(out.Content, anthropicToolUseBlock{
+					Type:  "tool_use",
+					ID:    geminiToolUseID(part.FunctionCall),
+					Name:  part.FunctionCall.Name,
+					Input: toolInput(string(part.FunctionCall.Args)),
+				})
+			default:
+				text.WriteString(part.Text)
+			}
+		}
+		flushText()
+	}
+	stopReason := geminiStopReason(finishReason, calledTools)
+	out.StopReason = &stopReason
+	if in.UsageMetadata != nil {
+		out.Usage = in.UsageMetadata.anthropicUsage()
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	replaceJSONBody(resp, converted)
+	return nil
+}
+
+// geminiMessageID derives an Anthropic message ID from a Gemini response ID
+func geminiMessageID(responseID string) string {
+	if responseID == "" {
+		return randomID("msg_")
+	}
+	return "msg_" + responseID
+}
+
+// geminiToolUseID returns the ID of a function call, generating one when
+// Gemini did not send it
+func geminiToolUseID(call *geminiFunctionCall) string {
+	if call.ID != "" {
+		return call.ID
+	}
+	return newToolUseID()
+}
+
+// geminiStream converts streamGenerateContent chunks into Anthropic
+// message events. Function calls arrive whole, in a single chunk.
+type geminiStream struct {
+	events      anthropicEvents
+	calledTools bool
+}
+
+func (s *geminiStream) convert(data []byte) []byte {
+	if s.events.stopped {
+		return nil
+	}
+
+	var chunk geminiResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil
+	}
+	if chunk.Error != nil {
+		return s.events.fail(nil, "api_error", upstreamErrorMessage(data, http.StatusBadGateway))
+	}
+
+	if s.events.id == "" {
+		s.events.id = geminiMessageID(chunk.ResponseID)
+	}
+	if chunk.UsageMetadata != nil {
+		s.events.usage = chunk.UsageMetadata.anthropicUsage()
+	}
+
+	var out []byte
+	for _, candidate := range chunk.Candidates {
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+			case part.FunctionCall != nil:
+				s.calledTools = true
+				out = s.events.toolUse(out, geminiToolUseID(part.FunctionCall), part.FunctionCall.Name)
+				out = s.events.toolInput(out, string(toolInput(string(part.FunctionCall.Args))))
+			case part.Text != "":
+				out = s.events.text(out, part.Text)
+			}
+		}
+		if candidate.FinishReason != "" {
+			s.events.stopReason = geminiStopReason(candidate.FinishReason, s.calledTools)
+		}
+	}
+	if out == nil {
+		out = s.events.start(nil)
+	}
+	return out
+}
+
+func (s *geminiStream) finish() []byte {
+	if s.events.stopReason == "" && s.calledTools {
+		s.events.stopReason = "tool_use"
+	}
+	return s.events.stop(nil)
+}
+
+// geminiProvider talks to the Gemini API with an API key
+type geminiProvider struct {
+	baseProvider
+}
+
+// ParseModels reads a Gemini model listing, whose names carry a
+// "models/" prefix
+func (p geminiProvider) ParseModels(body []byte) ([]string, error) {
+	var listing struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("invalid model listing: %w", err)
+	}
+
+	models := make([]string, 0, len(listing.Models))
+	for _, model := range listing.Models {
+		models = append(models, strings.TrimPrefix(model.Name, "models/"))
+	}
+	return models, nil
+}
+
+// Usage reads the usage metadata of a response or stream chunk. Thinking
+// tokens count as output.
+func (p geminiProvider) Usage(payload []byte) (TokenUsage, bool) {
+	var body struct {
+		UsageMetadata *geminiUsage `json:"usageMetadata"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil || body.UsageMetadata == nil {
+		return TokenUsage{}, false
+	}
+	usage := body.UsageMetadata
+	return TokenUsage{
+		InputTokens:  int64(usage.PromptTokenCount),
+		OutputTokens: int64(usage.CandidatesTokenCount + usage.ThoughtsTokenCount),
+	}, true
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_HandleRequest_WithGeminiProtocol_ShouldTranslate(t *testing.T) {
+	// Arrange - Gemini sends function calls without IDs
+	calls := 0
+	newToolUseID = func() string {
+		calls++
+		return fmt.Sprintf("toolu_gemini_%d", calls)
+	}
+	defer func() { newToolUseID = func() string { return randomID("toolu_") } }()
+
+	tests := []translationCase{
+		{dir: "gemini/text", status: http.StatusOK, upstreamPath: "/v1beta/models/gemini-2.5-pro:generateContent"},
+		{dir: "gemini/tools", status: http.StatusOK, upstreamPath: "/v1beta/models/gemini-2.5-flash:generateContent"},
+		{dir: "gemini/stream", status: http.StatusOK, upstreamPath: "/v1beta/models/gemini-2.5-flash:streamGenerateContent"},
+		{dir: "gemini/error", status: http.StatusTooManyRequests, upstreamPath: "/v1beta/models/gemini-2.5-pro:generateContent"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.dir, func(t *testing.T) {
+			tc.path = "/v1/messages"
+			runTranslationCase(t, ProtocolGemini, tc)
+		})
+	}
+}
+
+func TestAnthropicToGemini_TranslateRequest_WithStream_ShouldAskForSSE(t *testing.T) {
+	// Arrange
+	req, _ := http.NewRequest("POST", "http://localhost/v1/messages?beta=true", nil)
+	tr := &anthropicToGemini{}
+
+	// Act
+	translated, _, err := tr.translateRequest(req, []byte(`{"model":"gemini-2.5-pro","stream":true,"messages":[]}`))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1beta/models/gemini-2.5-pro:streamGenerateContent", translated.URL.Path)
+	assert.Equal(t, "sse", translated.URL.Query().Get("alt"))
+	assert.Equal(t, "gemini-2.5-pro", tr.model)
+}
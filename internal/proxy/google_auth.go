@@ -0,0 +1,253 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+const (
+	// googleTokenURL is the OAuth endpoint service accounts use by default
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	// googleCloudScope grants access to Vertex AI
+	googleCloudScope = "https://www.googleapis.com/auth/cloud-platform"
+	// googleTokenLifetime is the lifetime asked for in the JWT grant
+	googleTokenLifetime = time.Hour
+	// googleTokenRefreshMargin is how long before expiry a token is replaced
+	googleTokenRefreshMargin = 5 * time.Minute
+	// googleDefaultExpiresIn is assumed when a token response has no expires_in
+	googleDefaultExpiresIn = time.Hour
+)
+
+// googleServiceAccount holds the fields of a service-account JSON key
+// needed to mint access tokens
+type googleServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// googleCredentialsFile returns the service-account key of api, falling
+// back to GOOGLE_APPLICATION_CREDENTIALS
+func googleCredentialsFile(api *config.APIConfig) (string, error) {
+	if api.CredentialsFile != "" {
+		return api.CredentialsFile, nil
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path, nil
+	}
+	return "", fmt.Errorf("no credentials_file configured for API '%s'", api.ID)
+}
+
+// readServiceAccount loads a service-account JSON key
+func readServiceAccount(path string) (*googleServiceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account: %w", err)
+	}
+
+	var account googleServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid service account %s: %w", path, err)
+	}
+	if account.Type != "service_account" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("%s is not a service account key", path)
+	}
+	return &account, nil
+}
+
+// signer parses the private key of the service account
+func (a *googleServiceAccount) signer() (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(a.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("service account %s has no PEM private key", a.ClientEmail)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of service account %s: %w", a.ClientEmail, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key of service account %s is not RSA", a.ClientEmail)
+	}
+	return key, nil
+}
+
+// assertion builds the signed JWT exchanged for an access token
+func (a *googleServiceAccount) assertion(audience string, now time.Time) (string, error) {
+	key, err := a.signer()
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": a.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   a.ClientEmail,
+		"scope": googleCloudScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(googleTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// googleToken is a cached access token
+type googleToken struct {
+	value  string
+	expiry time.Time
+}
+
+// googleTokenEntry holds the token of one service account and token URL.
+// Its lock is held while a token is minted.
+type googleTokenEntry struct {
+	mu    sync.Mutex
+	token googleToken
+}
+
+// googleTokenCache mints access tokens for service accounts and reuses
+// them until shortly before they expire
+type googleTokenCache struct {
+	mu      sync.Mutex
+	entries map[string]*googleTokenEntry
+	client  *http.Client
+	now     func() time.Time
+}
+
+// newGoogleTokenCache creates an empty token cache
+func newGoogleTokenCache() *googleTokenCache {
+	return &googleTokenCache{
+		entries: map[string]*googleTokenEntry{},
+		client:  &http.Client{Timeout: 30 * time.Second},
+		now:     time.Now,
+	}
+}
+
+// entry returns the cache entry of key, creating it on first use
+func (c *googleTokenCache) entry(key string) *googleTokenEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &googleTokenEntry{}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// token returns a valid access token for the service account of api.
+// Concurrent callers for the same account wait for a single mint instead of
+// racing, without holding up other accounts.
+func (c *googleTokenCache) token(ctx context.Context, api *config.APIConfig) (string, error) {
+	path, err := googleCredentialsFile(api)
+	if err != nil {
+		return "", err
+	}
+	entry := c.entry(path + "\x00" + api.TokenURL)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.token.value != "" && c.now().Add(googleTokenRefreshMargin).Before(entry.token.expiry) {
+		return entry.token.value, nil
+	}
+
+	account, err := readServiceAccount(path)
+	if err != nil {
+		return "", err
+	}
+	tokenURL := api.TokenURL
+	if tokenURL == "" {
+		tokenURL = account.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = googleTokenURL
+	}
+
+	minted, err := c.mint(ctx, account, tokenURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token for API '%s': %w", api.ID, err)
+	}
+	entry.token = minted
+	return minted.value, nil
+}
+
+// mint exchanges a signed JWT for an access token
+func (c *googleTokenCache) mint(ctx context.Context, account *googleServiceAccount, tokenURL string) (googleToken, error) {
+	now := c.now()
+	assertion, err := account.assertion(tokenURL, now)
+	if err != nil {
+		return googleToken{}, err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return googleToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return googleToken{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorPeek))
+	if err != nil {
+		return googleToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return googleToken{}, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return googleToken{}, fmt.Errorf("invalid token response: %s", strings.TrimSpace(string(body)))
+	}
+	expiresIn := time.Duration(token.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = googleDefaultExpiresIn
+	}
+	return googleToken{value: token.AccessToken, expiry: now.Add(expiresIn)}, nil
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// viaPseudonym identifies the proxy in Via headers
+const viaPseudonym = "octopus"
+
+// hopHeaders are the hop-by-hop headers of RFC 7230 section 6.1, which apply
+// to a single connection and must not be forwarded
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection", // non-standard but still sent by some clients
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders deletes hop-by-hop headers, including any header the
+// Connection header names
+func removeHopHeaders(h http.Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// copyRequestHeaders copies the end-to-end headers of the inbound request to
+// the upstream request. The inbound Host is never copied, so the upstream
+// sees its own host name.
+//
+// Accept-Encoding is dropped as well: the transport then negotiates gzip on
+// its own and hands back a decoded body, which keeps event streams readable
+// line by line. The client hop is local, so it gains nothing from compression.
+func copyRequestHeaders(dst http.Header, src http.Header) {
+	for name, values := range src {
+		for _, value := range values {
+			dst.Add(name, value)
+		}
+	}
+	removeHopHeaders(dst)
+	dst.Del("Host")
+	dst.Del("Accept-Encoding")
+}
+
+// addForwardedHeaders records the client and the proxy hop on the upstream
+// request with X-Forwarded-For, X-Forwarded-Host, X-Forwarded-Proto and Via
+func addForwardedHeaders(dst http.Header, in *http.Request) {
+	if clientIP, _, err := net.SplitHostPort(in.RemoteAddr); err == nil {
+		if prior := in.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		dst.Set("X-Forwarded-For", clientIP)
+	}
+
+	if in.Host != "" {
+		dst.Set("X-Forwarded-Host", in.Host)
+	}
+
+	proto := "http"
+	if in.TLS != nil {
+		proto = "https"
+	}
+	dst.Set("X-Forwarded-Proto", proto)
+
+	dst.Add("Via", fmt.Sprintf("%d.%d %s", in.ProtoMajor, in.ProtoMinor, viaPseudonym))
+}
+
+// copyResponseHeaders copies the end-to-end headers of an upstream response
+func copyResponseHeaders(dst http.Header, src http.Header) {
+	for name, values := range src {
+		for _, value := range values {
+			dst.Add(name, value)
+		}
+	}
+	removeHopHeaders(dst)
+}
+
+// joinURL builds the upstream URL for an inbound request. Any path in the
+// base URL is kept as a prefix, e.g. https://gw.example.com/anthropic plus
+// /v1/messages gives https://gw.example.com/anthropic/v1/messages, and query
+// parameters of both are kept.
+func joinURL(base string, in *url.URL) (*url.URL, error) {
+	target, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %w", base, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid API URL %q: scheme and host are required", base)
+	}
+
+	target.Path, target.RawPath = joinURLPath(target, in)
+
+	switch {
+	case target.RawQuery == "":
+		target.RawQuery = in.RawQuery
+	case in.RawQuery != "":
+		target.RawQuery += "&" + in.RawQuery
+	}
+
+	return target, nil
+}
+
+// joinURLPath joins two URL paths with exactly one slash between them,
+// keeping the escaped form when either path has one
+func joinURLPath(a, b *url.URL) (path, rawPath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+
+	return singleJoiningSlash(a.Path, b.Path), singleJoiningSlash(a.EscapedPath(), b.EscapedPath())
+}
+
+// singleJoiningSlash joins a and b with a single slash
+func singleJoiningSlash(a, b string) string {
+	aSlash := strings.HasSuffix(a, "/")
+	bSlash := strings.HasPrefix(b, "/")
+	switch {
+	case aSlash && bSlash:
+		return a + b[1:]
+	case !aSlash && !bSlash && b != "":
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestJoinURL_WithBasePaths_ShouldJoinWithSingleSlash(t *testing.T) {
+	tests := []struct {
+		base     string
+		request  string
+		expected string
+	}{
+		{"https://api.example.com", "/v1/messages", "https://api.example.com/v1/messages"},
+		{"https://api.example.com/", "/v1/messages", "https://api.example.com/v1/messages"},
+		{"https://gw.example.com/anthropic", "/v1/messages", "https://gw.example.com/anthropic/v1/messages"},
+		{"https://gw.example.com/anthropic/", "/v1/messages?beta=true", "https://gw.example.com/anthropic/v1/messages?beta=true"},
+		{"https://gw.example.com/api?tenant=a", "/v1/messages?beta=true", "https://gw.example.com/api/v1/messages?tenant=a&beta=true"},
+		{"https://gw.example.com/a%2Fb", "/v1/x%2Fy", "https://gw.example.com/a%2Fb/v1/x%2Fy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base+tt.request, func(t *testing.T) {
+			// Arrange
+			in, err := url.Parse(tt.request)
+			require.NoError(t, err)
+
+			// Act
+			target, err := joinURL(tt.base, in)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, target.String())
+		})
+	}
+}
+
+func TestJoinURL_WithoutScheme_ShouldReturnError(t *testing.T) {
+	// Act
+	_, err := joinURL("api.example.com", &url.URL{Path: "/v1/messages"})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestCopyRequestHeaders_ShouldStripHopByHopHeaders(t *testing.T) {
+	// Arrange
+	src := http.Header{}
+	src.Set("Connection", "keep-alive, X-Session-Hop")
+	src.Set("Keep-Alive", "timeout=5")
+	src.Set("Transfer-Encoding", "chunked")
+	src.Set("Upgrade", "websocket")
+	src.Set("Proxy-Authorization", "Basic abc")
+	src.Set("X-Session-Hop", "1")
+	src.Set("Host", "localhost:8080")
+	src.Set("Accept-Encoding", "br")
+	src.Set("X-Custom", "kept")
+	dst := http.Header{}
+
+	// Act
+	copyRequestHeaders(dst, src)
+
+	// Assert
+	assert.Equal(t, http.Header{"X-Custom": {"kept"}}, dst)
+}
+
+func TestServer_HandleRequest_WithBasePathAndHopHeaders_ShouldForwardCleanly(t *testing.T) {
+	// Arrange
+	received := make(chan *http.Request, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Clone(r.Context())
+		w.Header().Set("Connection", "X-Upstream-Hop")
+		w.Header().Set("X-Upstream-Hop", "1")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "ok")
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL + "/anthropic"}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/v1/models?limit=5", server.GetPort()), nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("X-Upstream-Hop"))
+
+	upstream := <-received
+	assert.Equal(t, "/anthropic/v1/models", upstream.URL.Path)
+	assert.Equal(t, "limit=5", upstream.URL.RawQuery)
+	assert.Equal(t, strings.TrimPrefix(targetServer.URL, "http://"), upstream.Host)
+	assert.Empty(t, upstream.Header.Get("X-Client-Hop"))
+	assert.Empty(t, upstream.Header.Get("X-Forwarded-For"))
+	assert.Empty(t, upstream.Header.Get("Via"))
+}
+
+func TestServer_HandleRequest_WithForwardedHeaders_ShouldAddThem(t *testing.T) {
+	// Arrange
+	received := make(chan http.Header, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL}},
+		Settings: config.Settings{ActiveAPI: "target", ForwardedHeaders: true},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	proxyHost := fmt.Sprintf("localhost:%d", server.GetPort())
+	req, err := http.NewRequest("GET", "http://"+proxyHost+"/v1/models", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	headers := <-received
+	assert.Equal(t, "10.0.0.1, 127.0.0.1", headers.Get("X-Forwarded-For"))
+	assert.Equal(t, proxyHost, headers.Get("X-Forwarded-Host"))
+	assert.Equal(t, "http", headers.Get("X-Forwarded-Proto"))
+	assert.Equal(t, "1.1 octopus", headers.Get("Via"))
+}
+
+func TestServer_HandleRequest_WithGzipUpstream_ShouldRelayDecodedBody(t *testing.T) {
+	// Arrange
+	received := make(chan string, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, "plain body")
+		gz.Close()
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/v1/models", server.GetPort()), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "br")
+
+	// Act
+	resp, err := http.DefaultTransport.RoundTrip(req)
+
+	// Assert - the client's encoding is replaced by the transport's own gzip
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "gzip", <-received)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "plain body", string(body))
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// listenOn opens a listener on address. Unix sockets are restricted to
+// their owner so that filesystem permissions decide who may use the proxy.
+func listenOn(address config.Listener) (net.Listener, error) {
+	if address.Network != "unix" {
+		return net.Listen(address.Network, address.Address)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(address.Address), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if err := removeStaleSocket(address.Address); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", address.Address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(address.Address, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a proxy that did
+// not shut down cleanly. A path in use or holding anything but a socket is
+// left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// listenerURL describes a listener the way clients address it
+func listenerURL(listener net.Listener, scheme string) string {
+	if listener.Addr().Network() == "unix" {
+		return "unix://" + listener.Addr().String()
+	}
+	return scheme + "://" + listener.Addr().String()
+}
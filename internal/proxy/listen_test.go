@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// shortSocketDir returns a directory whose socket paths stay within the
+// length limit of unix socket addresses, unlike t.TempDir()
+func shortSocketDir(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not enforced on Windows")
+	}
+	dir, err := os.MkdirTemp("", "octopus")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// unixSocketClient returns a client sending every request to socket
+func unixSocketClient(socket string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+}
+
+func TestServer_Start_WithUnixAndTCPListeners_ShouldServeBoth(t *testing.T) {
+	// Arrange
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer targetServer.Close()
+
+	socket := filepath.Join(shortSocketDir(t), "octopus.sock")
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Listen: []string{"tcp://127.0.0.1:0", "unix://" + socket}},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+
+	// Act
+	require.NoError(t, server.Start())
+	viaSocket, socketErr := unixSocketClient(socket).Get("http://octopus/v1/models")
+	viaTCP, tcpErr := http.Get(fmt.Sprintf("http://127.0.0.1:%d/v1/models", server.GetPort()))
+	info, statErr := os.Stat(socket)
+	require.NoError(t, server.Stop())
+
+	// Assert
+	require.NoError(t, socketErr)
+	require.NoError(t, tcpErr)
+	defer viaSocket.Body.Close()
+	defer viaTCP.Body.Close()
+	body, _ := io.ReadAll(viaSocket.Body)
+	assert.Equal(t, `{"ok":true}`, string(body))
+	assert.Equal(t, http.StatusOK, viaTCP.StatusCode)
+
+	require.NoError(t, statErr)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.NoFileExists(t, socket, "the socket is removed on shutdown")
+}
+
+func TestServer_Start_WithStaleSocket_ShouldReplaceIt(t *testing.T) {
+	// Arrange - a socket file nobody listens on, as left by a crash
+	socket := filepath.Join(shortSocketDir(t), "octopus.sock")
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	require.FileExists(t, socket)
+
+	server := NewServer(&config.Config{Server: config.ServerConfig{Listen: []string{"unix://" + socket}}})
+
+	// Act
+	err = server.Start()
+
+	// Assert
+	require.NoError(t, err)
+	server.Stop()
+}
+
+func TestServer_Start_WithSocketInUse_ShouldReturnError(t *testing.T) {
+	// Arrange
+	socket := filepath.Join(shortSocketDir(t), "octopus.sock")
+	first := NewServer(&config.Config{Server: config.ServerConfig{Listen: []string{"unix://" + socket}}})
+	require.NoError(t, first.Start())
+	defer first.Stop()
+
+	second := NewServer(&config.Config{Server: config.ServerConfig{Listen: []string{"unix://" + socket}}})
+
+	// Act
+	err := second.Start()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "in use")
+}
@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"octopus-cli/internal/config"
+)
+
+// upstreamModel returns the name api knows model by, following its model map
+func upstreamModel(api *config.APIConfig, model string) string {
+	if mapped, ok := api.ModelMap[model]; ok && mapped != "" {
+		return mapped
+	}
+	return model
+}
+
+// mapRequestModel returns the body to send to api, with its model field
+// renamed when the API maps it, along with the model name the API will see
+func (s *Server) mapRequestModel(api *config.APIConfig, body *requestBody, model string) (*requestBody, string) {
+	mapped := upstreamModel(api, model)
+	if mapped == model {
+		return body, model
+	}
+
+	rewritten, err := body.withModel(mapped)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("Cannot map model '%s' to '%s' for API '%s': %v", model, mapped, api.ID, err)
+		}
+		return body, model
+	}
+
+	if s.logger != nil {
+		s.logger.Debug("Mapped model '%s' to '%s' for API '%s'", model, mapped, api.ID)
+	}
+	return rewritten, mapped
+}
+
+// withModel returns a copy of a JSON body with its top-level model field
+// set to model. Other fields keep their values, numbers included, but the
+// body is re-encoded: keys are sorted and insignificant whitespace dropped.
+func (b *requestBody) withModel(model string) (*requestBody, error) {
+	if !b.replayable() {
+		return nil, fmt.Errorf("request body is too large to rewrite")
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(b.data, &payload); err != nil {
+		return nil, fmt.Errorf("request body is not a JSON object: %w", err)
+	}
+
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	payload["model"] = encoded
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &requestBody{data: data}, nil
+}
+
+// rewriteModelField renames the model reported in a JSON payload from one
+// name to another. Both the top-level field and the one nested under
+// "message", as sent in streamed message_start events, are rewritten.
+// Payloads that do not mention the model are returned unchanged.
+func rewriteModelField(data []byte, from, to string) []byte {
+	if !bytes.Contains(data, []byte(from)) {
+		return data
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return data
+	}
+
+	changed := replaceModel(payload, from, to)
+	if raw, ok := payload["message"]; ok {
+		var message map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &message); err == nil && replaceModel(message, from, to) {
+			if encoded, err := json.Marshal(message); err == nil {
+				payload["message"] = encoded
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return data
+	}
+	rewritten, err := json.Marshal(payload)
+	if err != nil {
+		return data
+	}
+	return rewritten
+}
+
+// replaceModel swaps the "model" field of payload when it equals from
+func replaceModel(payload map[string]json.RawMessage, from, to string) bool {
+	var model string
+	if err := json.Unmarshal(payload["model"], &model); err != nil || model != from {
+		return false
+	}
+	encoded, err := json.Marshal(to)
+	if err != nil {
+		return false
+	}
+	payload["model"] = encoded
+	return true
+}
+
+// rewriteResponseModel makes resp report model from as model to. Event
+// streams are rewritten event by event as they are read; other bodies are
+// read in full and rewritten as a single JSON document.
+func rewriteResponseModel(resp *http.Response, from, to string) error {
+	rewrite := func(data []byte) []byte {
+		return rewriteModelField(data, from, to)
+	}
+
+	if isEventStream(resp) {
+		resp.Body = newEventDataRewriter(resp.Body, rewrite)
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	data = rewrite(data)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	if resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	}
+	return nil
+}
+
+// eventDataRewriter passes a server-sent event stream through line by line,
+// rewriting the payload of every data line
+type eventDataRewriter struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	rewrite func([]byte) []byte
+	pending []byte
+	err     error
+}
+
+// newEventDataRewriter wraps an event stream body with a data line rewriter
+func newEventDataRewriter(body io.ReadCloser, rewrite func([]byte) []byte) *eventDataRewriter {
+	return &eventDataRewriter{body: body, reader: bufio.NewReader(body), rewrite: rewrite}
+}
+
+func (e *eventDataRewriter) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.err != nil {
+			return 0, e.err
+		}
+
+		line, err := e.reader.ReadBytes('\n')
+		e.err = err
+		e.pending = e.rewriteLine(line)
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// rewriteLine applies the rewrite to the payload of a data line, keeping
+// the field name and line terminator as they were
+func (e *eventDataRewriter) rewriteLine(line []byte) []byte {
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return line
+	}
+
+	payload := bytes.TrimPrefix(line, []byte("data:"))
+	body := bytes.TrimLeft(payload, " ")
+	prefix := line[:len(line)-len(body)]
+	content := bytes.TrimRight(body, "\r\n")
+	terminator := body[len(content):]
+
+	rewritten := e.rewrite(content)
+	if bytes.Equal(rewritten, content) {
+		return line
+	}
+
+	out := make([]byte, 0, len(prefix)+len(rewritten)+len(terminator))
+	out = append(out, prefix...)
+	out = append(out, rewritten...)
+	return append(out, terminator...)
+}
+
+func (e *eventDataRewriter) Close() error {
+	return e.body.Close()
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestRewriteModelField_ShouldRewriteTopLevelAndMessageStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"top level", `{"id":"msg_1","model":"upstream-x"}`, `{"id":"msg_1","model":"claude-x"}`},
+		{"message start", `{"message":{"id":"msg_1","model":"upstream-x"},"type":"message_start"}`, `{"message":{"id":"msg_1","model":"claude-x"},"type":"message_start"}`},
+		{"other model", `{"model":"upstream-y"}`, `{"model":"upstream-y"}`},
+		{"no model", `{"type":"ping"}`, `{"type":"ping"}`},
+		{"not json", `[DONE]`, `[DONE]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			rewritten := rewriteModelField([]byte(tt.data), "upstream-x", "claude-x")
+
+			// Assert
+			assert.Equal(t, tt.expected, string(rewritten))
+		})
+	}
+}
+
+func TestServer_HandleRequest_WithModelMap_ShouldRewriteRequestModel(t *testing.T) {
+	// Arrange
+	var hits int64
+	target := newCountingTarget("target", http.StatusOK, &hits)
+	defer target.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:       "target",
+			URL:      target.URL,
+			ModelMap: map[string]string{"claude-sonnet-4": "anthropic/claude-sonnet-4"},
+		}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	_, mapped := postThroughProxy(t, server, `{"max_tokens":1,"model":"claude-sonnet-4"}`)
+	_, unmapped := postThroughProxy(t, server, `{"model":"claude-haiku-4-5"}`)
+
+	// Assert
+	assert.Equal(t, `target:{"max_tokens":1,"model":"anthropic/claude-sonnet-4"}`, mapped)
+	assert.Equal(t, `target:{"model":"claude-haiku-4-5"}`, unmapped)
+}
+
+func TestServer_HandleRequest_WithRewriteResponseModel_ShouldRestoreModelInStream(t *testing.T) {
+	// Arrange
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\n")
+		fmt.Fprint(w, `data: {"message":{"model":"upstream-sonnet"},"type":"message_start"}`+"\n\n")
+		fmt.Fprint(w, "event: ping\n")
+		fmt.Fprint(w, `data: {"type":"ping"}`+"\n\n")
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:                   "target",
+			URL:                  targetServer.URL,
+			ModelMap:             map[string]string{"claude-sonnet-4": "upstream-sonnet"},
+			RewriteResponseModel: true,
+		}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), "application/json",
+		strings.NewReader(`{"model":"claude-sonnet-4","stream":true}`))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "event: message_start\n"+
+		`data: {"message":{"model":"claude-sonnet-4"},"type":"message_start"}`+"\n\n"+
+		"event: ping\n"+
+		`data: {"type":"ping"}`+"\n\n", string(body))
+}
+
+func TestServer_HandleRequest_WithRewriteResponseModel_ShouldFixContentLength(t *testing.T) {
+	// Arrange
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"model":"m","type":"message"}`)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:                   "target",
+			URL:                  targetServer.URL,
+			ModelMap:             map[string]string{"claude-opus-4-1": "m"},
+			RewriteResponseModel: true,
+		}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	status, body := postThroughProxy(t, server, `{"model":"claude-opus-4-1"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"model":"claude-opus-4-1","type":"message"}`, body)
+}
@@ -0,0 +1,481 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// openAIRequest is an OpenAI Chat Completions request
+type openAIRequest struct {
+	Model             string               `json:"model"`
+	Messages          []openAIMessage      `json:"messages"`
+	MaxTokens         int                  `json:"max_tokens,omitempty"`
+	Temperature       *float64             `json:"temperature,omitempty"`
+	TopP              *float64             `json:"top_p,omitempty"`
+	Stop              []string             `json:"stop,omitempty"`
+	Stream            bool                 `json:"stream,omitempty"`
+	StreamOptions     *openAIStreamOptions `json:"stream_options,omitempty"`
+	Tools             []openAITool         `json:"tools,omitempty"`
+	ToolChoice        interface{}          `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool                `json:"parallel_tool_calls,omitempty"`
+	User              string               `json:"user,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIMessage is a chat message. Content is a string, a list of
+// openAIContentPart, or null for assistant turns made only of tool calls.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    interface{}      `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// openAIToolCall is a tool call of an assistant message. In streamed
+// chunks Index identifies the call and only the first fragment has an ID.
+type openAIToolCall struct {
+	Index    *int               `json:"index,omitempty"`
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// openAIResponse is a complete chat completion
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   *string          `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// openAIChunk is one event of a streamed chat completion
+type openAIChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage     `json:"usage"`
+	Error *json.RawMessage `json:"error"`
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// openAIErrorBody is the error format of the OpenAI API
+type openAIErrorBody struct {
+	Error struct {
+		Message    string          `json:"message"`
+		Type       string          `json:"type"`
+		Param      *string         `json:"param"`
+		Code       *string         `json:"code"`
+		InnerError json.RawMessage `json:"innererror,omitempty"`
+	} `json:"error"`
+}
+
+// openAIErrorType maps an HTTP status to the matching OpenAI error type
+func openAIErrorType(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status == http.StatusForbidden:
+		return "permission_error"
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status >= 400 && status < 500:
+		return "invalid_request_error"
+	}
+	return "server_error"
+}
+
+// anthropicUsage converts token counts, reporting cached prompt tokens
+// the way Anthropic does
+func (u *openAIUsage) anthropicUsage() anthropicUsage {
+	usage := anthropicUsage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		usage.CacheReadInputTokens = u.PromptTokensDetails.CachedTokens
+		usage.InputTokens -= u.PromptTokensDetails.CachedTokens
+	}
+	return usage
+}
+
+// openAIStopReason maps an OpenAI finish_reason to an Anthropic stop_reason
+func openAIStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	}
+	return "end_turn"
+}
+
+// anthropicToOpenAI lets Anthropic clients such as Claude Code talk to
+// OpenAI-compatible backends
+type anthropicToOpenAI struct{}
+
+func (t *anthropicToOpenAI) translateRequest(r *http.Request, body []byte) (*http.Request, []byte, error) {
+	var in anthropicRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, nil, fmt.Errorf("invalid Anthropic request: %w", err)
+	}
+
+	out := openAIRequest{
+		Model:       in.Model,
+		MaxTokens:   in.MaxTokens,
+		Temperature: in.Temperature,
+		TopP:        in.TopP,
+		Stop:        in.StopSequences,
+		Stream:      in.Stream,
+	}
+	if in.Stream {
+		out.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	if in.Metadata != nil {
+		out.User = in.Metadata.UserID
+	}
+
+	system, err := anthropicText(in.System)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid system prompt: %w", err)
+	}
+	if system != "" {
+		out.Messages = append(out.Messages, openAIMessage{Role: "system", Content: system})
+	}
+
+	for _, message := range in.Messages {
+		messages, err := openAIMessages(message)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.Messages = append(out.Messages, messages...)
+	}
+
+	for _, tool := range in.Tools {
+		out.Tools = append(out.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if choice := in.ToolChoice; choice != nil {
+		switch choice.Type {
+		case "auto":
+			out.ToolChoice = "auto"
+		case "any":
+			out.ToolChoice = "required"
+		case "none":
+			out.ToolChoice = "none"
+		case "tool":
+			out.ToolChoice = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": choice.Name},
+			}
+		}
+		if choice.DisableParallelToolUse {
+			parallel := false
+			out.ParallelToolCalls = &parallel
+		}
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return withTranslatedPath(r, "/v1/messages", "/v1/chat/completions"), data, nil
+}
+
+// openAIMessages converts one Anthropic message. Tool results become
+// separate tool messages placed ahead of the rest of the user turn.
+func openAIMessages(message anthropicMessage) ([]openAIMessage, error) {
+	blocks, err := anthropicBlocks(message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Role == "assistant" {
+		var text strings.Builder
+		var calls []openAIToolCall
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				text.WriteString(block.Text)
+			case "tool_use":
+				input := string(block.Input)
+				if input == "" {
+					input = "{}"
+				}
+				calls = append(calls, openAIToolCall{
+					ID:       block.ID,
+					Type:     "function",
+					Function: openAIFunctionCall{Name: block.Name, Arguments: input},
+				})
+			}
+		}
+
+		out := openAIMessage{Role: "assistant", ToolCalls: calls}
+		if text.Len() > 0 || len(calls) == 0 {
+			out.Content = text.String()
+		}
+		return []openAIMessage{out}, nil
+	}
+
+	var messages []openAIMessage
+	var parts []openAIContentPart
+	hasImage := false
+	for _, block := range blocks {
+		switch block.Type {
+		case "tool_result":
+			content, err := anthropicText(block.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool result: %w", err)
+			}
+			messages = append(messages, openAIMessage{Role: "tool", ToolCallID: block.ToolUseID, Content: content})
+		case "text":
+			parts = append(parts, openAIContentPart{Type: "text", Text: block.Text})
+		case "image":
+			if url := imageURL(block.Source); url != "" {
+				hasImage = true
+				parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+			}
+		}
+	}
+
+	if len(parts) == 0 {
+		return messages, nil
+	}
+	if hasImage {
+		return append(messages, openAIMessage{Role: message.Role, Content: parts}), nil
+	}
+
+	// Plain text is sent as a string, which every compatible server accepts
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		texts[i] = part.Text
+	}
+	return append(messages, openAIMessage{Role: message.Role, Content: strings.Join(texts, "\n\n")}), nil
+}
+
+// imageURL returns an image source as a URL, inlining base64 data
+func imageURL(source *anthropicImageSource) string {
+	switch {
+	case source == nil:
+		return ""
+	case source.Type == "base64":
+		return "data:" + source.MediaType + ";base64," + source.Data
+	case source.Type == "url":
+		return source.URL
+	}
+	return ""
+}
+
+func (t *anthropicToOpenAI) translateResponse(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		data, err := readResponseBody(resp)
+		if err != nil {
+			return err
+		}
+		converted, err := json.Marshal(anthropicError(anthropicErrorType(resp.StatusCode), upstreamErrorMessage(data, resp.StatusCode)))
+		if err != nil {
+			return err
+		}
+		replaceJSONBody(resp, converted)
+		return nil
+	}
+
+	if isEventStream(resp) {
+		stream := &openAIStream{}
+		resp.Body = newEventStreamConverter(resp.Body, stream.convert, stream.finish)
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+		return nil
+	}
+
+	data, err := readResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	var in openAIResponse
+	if err := json.Unmarshal(data, &in); err != nil {
+		return fmt.Errorf("invalid OpenAI response: %w", err)
+	}
+
+	out := anthropicResponse{
+		ID:      in.ID,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   in.Model,
+		Content: []interface{}{},
+	}
+	stopReason := "end_turn"
+	if len(in.Choices) > 0 {
+		choice := in.Choices[0]
+		if choice.Message.Content != nil && *choice.Message.Content != "" {
+			out.Content = append(out.Content, anthropicTextBlock{Type: "text", Text: *choice.Message.Content})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			out.Content = append(out.Content, anthropicToolUseBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: toolInput(call.Function.Arguments),
+			})
+		}
+		stopReason = openAIStopReason(choice.FinishReason)
+	}
+	out.StopReason = &stopReason
+	if in.Usage != nil {
+		out.Usage = in.Usage.anthropicUsage()
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	replaceJSONBody(resp, converted)
+	return nil
+}
+
+// openAIStream converts streamed chat completion chunks into Anthropic
+// message events
+type openAIStream struct {
+	events anthropicEvents
+	// toolCall is the OpenAI index of the tool call being streamed
+	toolCall int
+	inTool   bool
+}
+
+func (s *openAIStream) convert(data []byte) []byte {
+	if s.events.stopped {
+		return nil
+	}
+	if string(data) == "[DONE]" {
+		return s.events.stop(nil)
+	}
+
+	var chunk openAIChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil
+	}
+	if chunk.Error != nil {
+		return s.events.fail(nil, "api_error", upstreamErrorMessage(data, http.StatusBadGateway))
+	}
+
+	if s.events.id == "" {
+		s.events.id = chunk.ID
+	}
+	if s.events.model == "" {
+		s.events.model = chunk.Model
+	}
+
+	var out []byte
+	if chunk.Usage != nil {
+		s.events.usage = chunk.Usage.anthropicUsage()
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			s.inTool = false
+			out = s.events.text(out, choice.Delta.Content)
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			index := 0
+			if call.Index != nil {
+				index = *call.Index
+			}
+			if call.ID != "" || !s.inTool || index != s.toolCall {
+				s.toolCall, s.inTool = index, true
+				out = s.events.toolUse(out, call.ID, call.Function.Name)
+			}
+			out = s.events.toolInput(out, call.Function.Arguments)
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.events.stopReason = openAIStopReason(*choice.FinishReason)
+		}
+	}
+	if out == nil {
+		// Announce the message as soon as the upstream answers
+		out = s.events.start(nil)
+	}
+	return out
+}
+
+func (s *openAIStream) finish() []byte {
+	return s.events.stop(nil)
+}
+
+// openAIProvider talks to OpenAI and compatible Chat Completions APIs
+type openAIProvider struct {
+	baseProvider
+}
+
+func (p openAIProvider) ParseModels(body []byte) ([]string, error) {
+	return parseModelIDs(body)
+}
+
+// Usage reads the usage of a completion or of the final streamed chunk
+func (p openAIProvider) Usage(payload []byte) (TokenUsage, bool) {
+	var body struct {
+		Usage *struct {
+			PromptTokens     *int64 `json:"prompt_tokens"`
+			CompletionTokens int64  `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil || body.Usage == nil || body.Usage.PromptTokens == nil {
+		return TokenUsage{}, false
+	}
+	return TokenUsage{InputTokens: *body.Usage.PromptTokens, OutputTokens: body.Usage.CompletionTokens}, true
+}
@@ -0,0 +1,24 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestServer_HandleRequest_WithOpenAIProtocol_ShouldTranslate(t *testing.T) {
+	tests := []translationCase{
+		{dir: "openai/text", status: http.StatusOK},
+		{dir: "openai/tools", status: http.StatusOK},
+		{dir: "openai/stream", status: http.StatusOK},
+		{dir: "openai/stream_tools", status: http.StatusOK},
+		{dir: "openai/error", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.dir, func(t *testing.T) {
+			tc.path = "/v1/messages"
+			tc.upstreamPath = "/v1/chat/completions"
+			runTranslationCase(t, ProtocolOpenAI, tc)
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"octopus-cli/internal/config"
+)
+
+// Provider names accepted in config.APIConfig.Provider
+const (
+	ProviderAnthropic   = "anthropic"
+	ProviderOpenAI      = "openai"
+	ProviderGemini      = "gemini"
+	ProviderAzureOpenAI = "azure-openai"
+	ProviderBedrock     = "bedrock"
+	ProviderVertex      = "vertex"
+	ProviderGeneric     = "generic"
+)
+
+// TokenUsage counts the tokens of a single response
+type TokenUsage struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// Provider captures what differs between upstream API vendors: how keys
+// are sent, where models are listed, and how errors and token usage are
+// reported
+type Provider interface {
+	// Name is the value of the provider field selecting this provider
+	Name() string
+	// Protocol is the API dialect the provider speaks, empty when requests
+	// are forwarded without translation
+	Protocol() string
+	// BaseURL returns the URL requests to api are sent to
+	BaseURL(api *config.APIConfig) (string, error)
+	// ApplyAuth adds the credentials of api to an upstream request
+	ApplyAuth(req *http.Request, api *config.APIConfig) error
+	// ModelsURL returns the endpoint listing the models of api. It also
+	// serves as a cheap health check.
+	ModelsURL(api *config.APIConfig) (string, error)
+	// ParseModels reads model names from a models endpoint response
+	ParseModels(body []byte) ([]string, error)
+	// ErrorMessage reads the human-readable message of an error response
+	ErrorMessage(body []byte, status int) string
+	// Usage reads token counts from a response body or a single streamed
+	// event, reporting false when the payload carries none
+	Usage(payload []byte) (TokenUsage, bool)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// RegisterProvider makes a provider available under its name, replacing
+// any provider registered before under the same name
+func RegisterProvider(provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[provider.Name()] = provider
+}
+
+// LookupProvider returns the provider registered under name
+func LookupProvider(name string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+// ProviderNames lists the registered providers in alphabetical order
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterProvider(anthropicProvider{baseProvider{ProviderAnthropic, ProtocolAnthropic, AuthStyleAnthropic, "/v1/models"}})
+	RegisterProvider(openAIProvider{baseProvider{ProviderOpenAI, ProtocolOpenAI, AuthStyleBearer, "/v1/models"}})
+	RegisterProvider(geminiProvider{baseProvider{ProviderGemini, ProtocolGemini, AuthStyleGoogle, "/v1beta/models"}})
+	RegisterProvider(azureOpenAIProvider{openAIProvider{baseProvider{ProviderAzureOpenAI, ProtocolOpenAI, AuthStyleAzure, "/openai/models"}}})
+	RegisterProvider(bedrockProvider{anthropicProvider{baseProvider{ProviderBedrock, ProtocolBedrock, "", ""}}})
+	RegisterProvider(vertexProvider{anthropicProvider{baseProvider{ProviderVertex, ProtocolVertex, "", ""}}, newGoogleTokenCache()})
+	RegisterProvider(genericProvider{baseProvider{ProviderGeneric, "", AuthStyleBearer, ""}})
+}
+
+// ProviderFor returns the provider of api. Without a provider field it is
+// inferred from the protocol, falling back to generic passthrough.
+func ProviderFor(api *config.APIConfig) (Provider, error) {
+	name := api.Provider
+	if name == "" {
+		switch api.Protocol {
+		case ProtocolAnthropic, ProtocolOpenAI, ProtocolGemini, ProtocolBedrock, ProtocolVertex:
+			name = api.Protocol
+		default:
+			name = ProviderGeneric
+		}
+	}
+
+	provider, ok := LookupProvider(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider '%s' for API '%s'", name, api.ID)
+	}
+	return provider, nil
+}
+
+// apiProtocol returns the protocol api speaks: its protocol field when
+// set, otherwise the one of its provider
+func apiProtocol(api *config.APIConfig) string {
+	if api.Protocol != "" {
+		return api.Protocol
+	}
+	if provider, err := ProviderFor(api); err == nil {
+		return provider.Protocol()
+	}
+	return ""
+}
+
+// NewModelsRequest builds an authenticated request listing the models of api
+func NewModelsRequest(ctx context.Context, api *config.APIConfig) (*http.Request, Provider, error) {
+	provider, err := ProviderFor(api)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := provider.ModelsURL(api)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := provider.ApplyAuth(req, api); err != nil {
+		return nil, nil, err
+	}
+	return req, provider, nil
+}
+
+// ListModels asks api for the models it serves
+func ListModels(ctx context.Context, client *http.Client, api *config.APIConfig) ([]string, error) {
+	req, provider, err := NewModelsRequest(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("failed to list models: %d %s", resp.StatusCode, provider.ErrorMessage(body, resp.StatusCode))
+	}
+	return provider.ParseModels(body)
+}
+
+// baseProvider implements the behavior providers share
+type baseProvider struct {
+	name       string
+	protocol   string
+	authStyle  string
+	modelsPath string
+}
+
+func (p baseProvider) Name() string { return p.name }
+
+func (p baseProvider) Protocol() string { return p.protocol }
+
+func (p baseProvider) BaseURL(api *config.APIConfig) (string, error) {
+	return api.URL, nil
+}
+
+func (p baseProvider) ApplyAuth(req *http.Request, api *config.APIConfig) error {
+	return applyAuthStyle(req, api, p.authStyle)
+}
+
+func (p baseProvider) ModelsURL(api *config.APIConfig) (string, error) {
+	if p.modelsPath == "" {
+		return api.URL, nil
+	}
+	target, err := joinURL(api.URL, &url.URL{Path: p.modelsPath})
+	if err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+func (p baseProvider) ErrorMessage(body []byte, status int) string {
+	return upstreamErrorMessage(body, status)
+}
+
+// parseModelIDs reads the "data[].id" listing used by Anthropic and OpenAI
+func parseModelIDs(body []byte) ([]string, error) {
+	var listing struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("invalid model listing: %w", err)
+	}
+
+	models := make([]string, 0, len(listing.Data))
+	for _, model := range listing.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
+
+// genericProvider forwards requests untouched and understands whichever
+// of the common response formats the upstream happens to use
+type genericProvider struct {
+	baseProvider
+}
+
+func (p genericProvider) ParseModels(body []byte) ([]string, error) {
+	models, err := parseModelIDs(body)
+	if err == nil && len(models) > 0 {
+		return models, nil
+	}
+	return geminiProvider{}.ParseModels(body)
+}
+
+func (p genericProvider) Usage(payload []byte) (TokenUsage, bool) {
+	for _, provider := range []Provider{anthropicProvider{}, openAIProvider{}, geminiProvider{}} {
+		if usage, ok := provider.Usage(payload); ok {
+			return usage, true
+		}
+	}
+	return TokenUsage{}, false
+}
+
+// maxErrorPeek is how much of an error response is read for its message
+const maxErrorPeek = 64 << 10
+
+// peekErrorMessage reads the message of an error response through its
+// provider, leaving the body intact for the client
+func peekErrorMessage(resp *http.Response, provider Provider) string {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorPeek))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil {
+		return err.Error()
+	}
+	return provider.ErrorMessage(data, resp.StatusCode)
+}
+
+// maxUsageBody is the largest non-streamed response scanned for usage
+const maxUsageBody = 4 << 20
+
+// usageRecorder watches a response body on its way to the client and
+// reports the token usage it carries once the body is closed
+type usageRecorder struct {
+	body     io.ReadCloser
+	provider Provider
+	stream   bool
+	report   func(TokenUsage)
+
+	buf   bytes.Buffer
+	usage TokenUsage
+	found bool
+	once  sync.Once
+}
+
+// recordUsage wraps the body of resp so report receives its token usage
+func recordUsage(resp *http.Response, provider Provider, report func(TokenUsage)) {
+	resp.Body = &usageRecorder{
+		body:     resp.Body,
+		provider: provider,
+		stream:   isEventStream(resp),
+		report:   report,
+	}
+}
+
+func (u *usageRecorder) Read(p []byte) (int, error) {
+	n, err := u.body.Read(p)
+	if n > 0 {
+		u.observe(p[:n])
+	}
+	return n, err
+}
+
+// observe buffers what was read. Streams are scanned line by line; other
+// bodies are kept whole, up to maxUsageBody, and scanned at the end.
+func (u *usageRecorder) observe(data []byte) {
+	if !u.stream {
+		if u.buf.Len()+len(data) <= maxUsageBody {
+			u.buf.Write(data)
+		}
+		return
+	}
+
+	u.buf.Write(data)
+	for {
+		line, err := u.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the partial line for the next read
+			rest := append([]byte(nil), line...)
+			u.buf.Reset()
+			u.buf.Write(rest)
+			return
+		}
+		u.scanLine(line)
+	}
+}
+
+// scanLine merges the usage of a data line into the running total. Every
+// vendor reports cumulative counts, so the latest non-zero value wins.
+func (u *usageRecorder) scanLine(line []byte) {
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	u.merge(payload)
+}
+
+func (u *usageRecorder) merge(payload []byte) {
+	usage, ok := u.provider.Usage(payload)
+	if !ok {
+		return
+	}
+	u.found = true
+	if usage.InputTokens > 0 {
+		u.usage.InputTokens = usage.InputTokens
+	}
+	if usage.OutputTokens > 0 {
+		u.usage.OutputTokens = usage.OutputTokens
+	}
+}
+
+func (u *usageRecorder) Close() error {
+	u.once.Do(func() {
+		if u.stream {
+			scanner := bufio.NewScanner(&u.buf)
+			for scanner.Scan() {
+				u.scanLine(scanner.Bytes())
+			}
+		} else {
+			u.merge(u.buf.Bytes())
+		}
+		if u.found {
+			u.report(u.usage)
+		}
+	})
+	return u.body.Close()
+}
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestProviderFor_ShouldUseFieldThenProtocolThenGeneric(t *testing.T) {
+	tests := []struct {
+		name     string
+		api      config.APIConfig
+		expected string
+	}{
+		{"explicit provider", config.APIConfig{Provider: ProviderAzureOpenAI}, ProviderAzureOpenAI},
+		{"inferred from protocol", config.APIConfig{Protocol: ProtocolGemini}, ProviderGemini},
+		{"provider wins over protocol", config.APIConfig{Provider: ProviderGeneric, Protocol: ProtocolOpenAI}, ProviderGeneric},
+		{"nothing set", config.APIConfig{}, ProviderGeneric},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			provider, err := ProviderFor(&tt.api)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, provider.Name())
+		})
+	}
+}
+
+func TestProviderFor_WithUnknownProvider_ShouldReturnError(t *testing.T) {
+	// Act
+	_, err := ProviderFor(&config.APIConfig{ID: "api", Provider: "bedrock-classic"})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestApplyAuth_ShouldDefaultToProviderAuthStyle(t *testing.T) {
+	tests := []struct {
+		provider string
+		header   string
+		value    string
+	}{
+		{ProviderAnthropic, "X-Api-Key", "real-key"},
+		{ProviderOpenAI, "Authorization", "Bearer real-key"},
+		{ProviderGemini, "X-Goog-Api-Key", "real-key"},
+		{ProviderAzureOpenAI, "Api-Key", "real-key"},
+		{ProviderGeneric, "Authorization", "Bearer real-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("POST", "/v1/messages", nil)
+			req.Header.Set("Authorization", "Bearer dummy")
+
+			// Act
+			err := applyAuth(req, &config.APIConfig{ID: "api", APIKey: "real-key", Provider: tt.provider})
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, req.Header.Get(tt.header))
+		})
+	}
+}
+
+func TestProvider_Usage_ShouldReadEachVendorsFormat(t *testing.T) {
+	tests := []struct {
+		provider string
+		payload  string
+		expected TokenUsage
+		found    bool
+	}{
+		{ProviderAnthropic, `{"type":"message","usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":5}}`, TokenUsage{100, 5}, true},
+		{ProviderAnthropic, `{"type":"message_start","message":{"usage":{"input_tokens":12,"output_tokens":1}}}`, TokenUsage{12, 1}, true},
+		{ProviderAnthropic, `{"type":"message_delta","usage":{"output_tokens":42}}`, TokenUsage{0, 42}, true},
+		{ProviderAnthropic, `{"type":"ping"}`, TokenUsage{}, false},
+		{ProviderOpenAI, `{"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3}}`, TokenUsage{7, 3}, true},
+		{ProviderOpenAI, `{"choices":[],"usage":null}`, TokenUsage{}, false},
+		{ProviderGemini, `{"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":2,"thoughtsTokenCount":4}}`, TokenUsage{8, 6}, true},
+		{ProviderGeneric, `{"usage":{"prompt_tokens":1,"completion_tokens":2}}`, TokenUsage{1, 2}, true},
+		{ProviderGeneric, `[DONE]`, TokenUsage{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+" "+tt.payload, func(t *testing.T) {
+			// Arrange
+			provider, ok := LookupProvider(tt.provider)
+			require.True(t, ok)
+
+			// Act
+			usage, found := provider.Usage([]byte(tt.payload))
+
+			// Assert
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, usage)
+		})
+	}
+}
+
+func TestListModels_ShouldCallProviderEndpointWithItsAuth(t *testing.T) {
+	// Arrange
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gw/v1beta/models" || r.Header.Get("X-Goog-Api-Key") != "real-key" {
+			http.Error(w, `{"error":{"code":401,"message":"API key not valid"}}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"models":[{"name":"models/gemini-2.5-pro"},{"name":"models/gemini-2.5-flash"}]}`)
+	}))
+	defer targetServer.Close()
+
+	api := &config.APIConfig{ID: "gemini", URL: targetServer.URL + "/gw", APIKey: "real-key", Provider: ProviderGemini}
+	badKey := *api
+	badKey.APIKey = "wrong"
+
+	// Act
+	models, err := ListModels(context.Background(), http.DefaultClient, api)
+	_, badErr := ListModels(context.Background(), http.DefaultClient, &badKey)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gemini-2.5-pro", "gemini-2.5-flash"}, models)
+	require.Error(t, badErr)
+	assert.Contains(t, badErr.Error(), "API key not valid")
+}
+
+func TestServer_HandleRequest_ShouldCountTokensReportedByProvider(t *testing.T) {
+	// Arrange - a streamed Anthropic response reports usage across events
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\n")
+		fmt.Fprint(w, `data: {"type":"message_start","message":{"usage":{"input_tokens":20,"output_tokens":1}}}`+"\n\n")
+		fmt.Fprint(w, "event: message_delta\n")
+		fmt.Fprint(w, `data: {"type":"message_delta","usage":{"output_tokens":15}}`+"\n\n")
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL, Provider: ProviderAnthropic}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	postThroughProxy(t, server, `{"model":"claude-sonnet-4","stream":true}`)
+	postThroughProxy(t, server, `{"model":"claude-sonnet-4","stream":true}`)
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		stats := server.GetStats().Upstreams["target"]
+		return stats.InputTokens == 40 && stats.OutputTokens == 30
+	}, time.Second, 10*time.Millisecond)
+}
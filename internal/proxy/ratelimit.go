@@ -0,0 +1,388 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// Defaults of the rate limit queue
+const (
+	defaultRateLimitQueueSize    = 100
+	defaultRateLimitQueueTimeout = 60 * time.Second
+)
+
+// errRateLimited is returned when a request cannot be sent within the rate
+// limits of any API of its chain
+var errRateLimited = errors.New("rate limit exceeded")
+
+// rateLimits are the limits configured for an API
+type rateLimits struct {
+	requestsPerMinute    int
+	inputTokensPerMinute int
+	maxConcurrent        int
+	queueSize            int
+	queueTimeout         time.Duration
+}
+
+// rateLimitsFor reads the limits of an API, filling in queue defaults
+func rateLimitsFor(api *config.APIConfig) rateLimits {
+	limits := rateLimits{
+		requestsPerMinute:    api.RequestsPerMinute,
+		inputTokensPerMinute: api.InputTokensPerMinute,
+		maxConcurrent:        api.MaxConcurrent,
+		queueSize:            api.QueueSize,
+		queueTimeout:         time.Duration(api.QueueTimeout) * time.Second,
+	}
+	if limits.queueSize <= 0 {
+		limits.queueSize = defaultRateLimitQueueSize
+	}
+	if limits.queueTimeout <= 0 {
+		limits.queueTimeout = defaultRateLimitQueueTimeout
+	}
+	return limits
+}
+
+// enabled reports whether any limit is set
+func (l rateLimits) enabled() bool {
+	return l.requestsPerMinute > 0 || l.inputTokensPerMinute > 0 || l.maxConcurrent > 0
+}
+
+// estimateInputTokens guesses the input tokens of a request body before the
+// upstream counts them, at roughly four bytes of JSON per token
+func estimateInputTokens(body []byte) int64 {
+	return int64(len(body))/4 + 1
+}
+
+// tokenBucket refills at perMinute units a minute up to perMinute, so a
+// full minute's budget may be spent in a burst. A zero rate is unlimited.
+type tokenBucket struct {
+	perMinute float64
+	level     float64
+	updated   time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) tokenBucket {
+	return tokenBucket{perMinute: float64(perMinute), level: float64(perMinute), updated: now}
+}
+
+// resize changes the rate, keeping the level within the new capacity
+func (b *tokenBucket) resize(perMinute int, now time.Time) {
+	b.refill(now)
+	b.perMinute = float64(perMinute)
+	if b.level > b.perMinute {
+		b.level = b.perMinute
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.level += elapsed.Minutes() * b.perMinute
+		if b.level > b.perMinute {
+			b.level = b.perMinute
+		}
+	}
+	b.updated = now
+}
+
+// cost caps n at the capacity so that no request waits forever
+func (b *tokenBucket) cost(n float64) float64 {
+	if n > b.perMinute {
+		return b.perMinute
+	}
+	return n
+}
+
+// wait returns how long until n units are available
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if b.perMinute == 0 || b.level >= b.cost(n) {
+		return 0
+	}
+	return time.Duration((b.cost(n) - b.level) / b.perMinute * float64(time.Minute))
+}
+
+func (b *tokenBucket) take(n float64) {
+	if b.perMinute > 0 {
+		b.level -= b.cost(n)
+	}
+}
+
+// rateLimiter admits requests to one API within its limits. Requests that
+// do not fit wait in a first-in first-out queue of bounded length.
+type rateLimiter struct {
+	mu       sync.Mutex
+	limits   rateLimits
+	requests tokenBucket
+	tokens   tokenBucket
+	inFlight int
+	queue    []chan struct{}
+	now      func() time.Time
+}
+
+func newRateLimiter(limits rateLimits, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		limits:   limits,
+		requests: newTokenBucket(limits.requestsPerMinute, now()),
+		tokens:   newTokenBucket(limits.inputTokensPerMinute, now()),
+		now:      now,
+	}
+}
+
+// setLimits applies changed limits, keeping the budget already spent
+func (l *rateLimiter) setLimits(limits rateLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limits == l.limits {
+		return
+	}
+	l.limits = limits
+	l.requests.resize(limits.requestsPerMinute, l.now())
+	l.tokens.resize(limits.inputTokensPerMinute, l.now())
+	l.wakeHead()
+}
+
+// tryTake admits a request estimated at tokens input tokens if the limits
+// allow it now. Otherwise it returns how long until the budgets refill,
+// zero when only a concurrency slot is missing. The caller must hold l.mu.
+func (l *rateLimiter) tryTake(tokens int64) (bool, time.Duration) {
+	now := l.now()
+	l.requests.refill(now)
+	l.tokens.refill(now)
+
+	wait := l.requests.wait(1)
+	if tokenWait := l.tokens.wait(float64(tokens)); tokenWait > wait {
+		wait = tokenWait
+	}
+	if wait > 0 || (l.limits.maxConcurrent > 0 && l.inFlight >= l.limits.maxConcurrent) {
+		return false, wait
+	}
+
+	l.requests.take(1)
+	l.tokens.take(float64(tokens))
+	l.inFlight++
+	return true, 0
+}
+
+// acquire waits until a request estimated at tokens input tokens may be
+// sent. It fails at once when the queue is full, and when the queue
+// timeout passes or ctx ends first. The returned function releases the
+// concurrency slot once the request is over.
+func (l *rateLimiter) acquire(ctx context.Context, tokens int64) (func(), error) {
+	l.mu.Lock()
+	if len(l.queue) == 0 {
+		if ok, _ := l.tryTake(tokens); ok {
+			l.mu.Unlock()
+			return l.release, nil
+		}
+	}
+	if len(l.queue) >= l.limits.queueSize {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d requests already queued", errRateLimited, len(l.queue))
+	}
+	wake := make(chan struct{}, 1)
+	l.queue = append(l.queue, wake)
+	deadline := time.NewTimer(l.limits.queueTimeout)
+	defer deadline.Stop()
+
+	for {
+		refill := time.NewTimer(time.Hour)
+		if l.queue[0] == wake {
+			ok, wait := l.tryTake(tokens)
+			if ok {
+				refill.Stop()
+				l.leave(wake)
+				l.mu.Unlock()
+				return l.release, nil
+			}
+			if wait > 0 {
+				refill.Reset(wait)
+			}
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-refill.C:
+		case <-deadline.C:
+			l.mu.Lock()
+			l.leave(wake)
+			l.mu.Unlock()
+			return nil, fmt.Errorf("%w: no capacity within %s", errRateLimited, l.limits.queueTimeout)
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.leave(wake)
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		refill.Stop()
+		l.mu.Lock()
+	}
+}
+
+// leave removes a waiter from the queue and lets the next one try. The
+// caller must hold l.mu.
+func (l *rateLimiter) leave(wake chan struct{}) {
+	for i, waiter := range l.queue {
+		if waiter == wake {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	l.wakeHead()
+}
+
+// wakeHead signals the first waiter to try again. The caller must hold l.mu.
+func (l *rateLimiter) wakeHead() {
+	if len(l.queue) == 0 {
+		return
+	}
+	select {
+	case l.queue[0] <- struct{}{}:
+	default:
+	}
+}
+
+// release frees the concurrency slot of a finished request
+func (l *rateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	l.wakeHead()
+}
+
+// takeRetry spends a request from the budget for a retry of a request that
+// already holds its concurrency slot. Retries skip the queue, since queued
+// requests may be waiting for that very slot. It fails when the budget does
+// not refill within the queue timeout.
+func (l *rateLimiter) takeRetry(ctx context.Context) bool {
+	deadline := l.now().Add(l.limits.queueTimeout)
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.requests.refill(now)
+		wait := l.requests.wait(1)
+		if wait == 0 {
+			l.requests.take(1)
+			l.mu.Unlock()
+			return true
+		}
+		l.mu.Unlock()
+
+		if now.Add(wait).After(deadline) {
+			return false
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// settle corrects the token budget once the upstream reports how many
+// input tokens a request really used
+func (l *rateLimiter) settle(estimated, actual int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tokens.perMinute == 0 {
+		return
+	}
+	l.tokens.refill(l.now())
+	l.tokens.level += l.tokens.cost(float64(estimated)) - float64(actual)
+	if l.tokens.level > l.tokens.perMinute {
+		l.tokens.level = l.tokens.perMinute
+	}
+	l.wakeHead()
+}
+
+// queued returns the number of waiting requests
+func (l *rateLimiter) queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queue)
+}
+
+// rateLimiterSet keeps a rate limiter per API
+type rateLimiterSet struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+	now      func() time.Time
+}
+
+func newRateLimiterSet() *rateLimiterSet {
+	return &rateLimiterSet{limiters: make(map[string]*rateLimiter), now: time.Now}
+}
+
+// get returns the limiter of an API, or nil when the API sets no limits.
+// Limits changed by a configuration reload apply to the existing limiter.
+func (s *rateLimiterSet) get(api *config.APIConfig) *rateLimiter {
+	limits := rateLimitsFor(api)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limiter, ok := s.limiters[api.ID]
+	if !ok {
+		if !limits.enabled() {
+			return nil
+		}
+		limiter = newRateLimiter(limits, s.now)
+		s.limiters[api.ID] = limiter
+		return limiter
+	}
+	limiter.setLimits(limits)
+	return limiter
+}
+
+// remove drops the limiter of an API. Requests already admitted or queued
+// keep using it.
+func (s *rateLimiterSet) remove(apiID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.limiters, apiID)
+}
+
+// queued returns the queue depth of every limited API
+func (s *rateLimiterSet) queued() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	depths := make(map[string]int, len(s.limiters))
+	for id, limiter := range s.limiters {
+		depths[id] = limiter.queued()
+	}
+	return depths
+}
+
+// admit waits until the rate limits of api allow a request with body. It
+// returns a function freeing the request's concurrency slot and one
+// correcting its input token estimate once the upstream reports usage.
+func (s *Server) admit(ctx context.Context, api *config.APIConfig, body *requestBody) (func(), func(inputTokens int64), error) {
+	limiter := s.limiters.get(api)
+	if limiter == nil {
+		return func() {}, func(int64) {}, nil
+	}
+
+	estimated := estimateInputTokens(body.data)
+	release, err := limiter.acquire(ctx, estimated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return release, func(actual int64) { limiter.settle(estimated, actual) }, nil
+}
+
+// retryGate returns a function charging each retry to the request budget of
+// api, or nil when the API sets no limits
+func (s *Server) retryGate(api *config.APIConfig) func(ctx context.Context) bool {
+	limiter := s.limiters.get(api)
+	if limiter == nil {
+		return nil
+	}
+	return limiter.takeRetry
+}
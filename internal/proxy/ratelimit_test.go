@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestRateLimiter_TryTake_ShouldRefillRequestBudgetOverTime(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	limiter := newRateLimiter(rateLimits{requestsPerMinute: 2, queueSize: 1, queueTimeout: time.Minute}, func() time.Time { return now })
+
+	// Act
+	first, _ := limiter.tryTake(1)
+	second, _ := limiter.tryTake(1)
+	third, wait := limiter.tryTake(1)
+	now = now.Add(30 * time.Second)
+	refilled, _ := limiter.tryTake(1)
+
+	// Assert
+	assert.True(t, first)
+	assert.True(t, second)
+	assert.False(t, third)
+	assert.Equal(t, 30*time.Second, wait)
+	assert.True(t, refilled)
+}
+
+func TestRateLimiter_Settle_ShouldReturnOverestimatedInputTokens(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	limiter := newRateLimiter(rateLimits{inputTokensPerMinute: 1000, queueSize: 1, queueTimeout: time.Minute}, func() time.Time { return now })
+	admitted, _ := limiter.tryTake(800)
+	require.True(t, admitted)
+
+	// Act
+	blocked, wait := limiter.tryTake(800)
+	limiter.settle(800, 100)
+	afterSettle, _ := limiter.tryTake(800)
+
+	// Assert
+	assert.False(t, blocked)
+	assert.Equal(t, 36*time.Second, wait)
+	assert.True(t, afterSettle)
+}
+
+func TestRateLimiter_Acquire_ShouldAdmitQueuedRequestsInOrder(t *testing.T) {
+	// Arrange
+	limiter := newRateLimiter(rateLimits{maxConcurrent: 1, queueSize: 2, queueTimeout: 5 * time.Second}, time.Now)
+	release, err := limiter.acquire(context.Background(), 1)
+	require.NoError(t, err)
+
+	admitted := make(chan string, 2)
+	wait := func(name string) {
+		next, err := limiter.acquire(context.Background(), 1)
+		if assert.NoError(t, err) {
+			admitted <- name
+			next()
+		}
+	}
+	go wait("second")
+	require.Eventually(t, func() bool { return limiter.queued() == 1 }, time.Second, time.Millisecond)
+	go wait("third")
+	require.Eventually(t, func() bool { return limiter.queued() == 2 }, time.Second, time.Millisecond)
+
+	// Act
+	_, fullErr := limiter.acquire(context.Background(), 1)
+	release()
+
+	// Assert
+	assert.ErrorIs(t, fullErr, errRateLimited)
+	assert.Equal(t, "second", <-admitted)
+	assert.Equal(t, "third", <-admitted)
+	assert.Zero(t, limiter.queued())
+}
+
+func TestRateLimiter_Acquire_WhenQueueTimeoutPasses_ShouldFail(t *testing.T) {
+	// Arrange
+	limiter := newRateLimiter(rateLimits{maxConcurrent: 1, queueSize: 1, queueTimeout: 20 * time.Millisecond}, time.Now)
+	_, err := limiter.acquire(context.Background(), 1)
+	require.NoError(t, err)
+
+	// Act
+	_, err = limiter.acquire(context.Background(), 1)
+
+	// Assert
+	assert.ErrorIs(t, err, errRateLimited)
+	assert.Zero(t, limiter.queued())
+}
+
+func TestServer_HandleRequest_WithRetriesOverRequestLimit_ShouldStayWithinLimit(t *testing.T) {
+	// Arrange
+	var hits int64
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:                "target",
+			URL:               targetServer.URL,
+			RetryCount:        5,
+			RequestsPerMinute: 2,
+			QueueTimeout:      1,
+		}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), "application/json", strings.NewReader(`{}`))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&hits), "retries beyond the request budget are not sent")
+}
+
+func TestServer_HandleRequest_WithConcurrencyLimit_ShouldQueueAndRejectOverflow(t *testing.T) {
+	// Arrange
+	arrived := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-unblock
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:            "target",
+			URL:           targetServer.URL,
+			MaxConcurrent: 1,
+			QueueSize:     1,
+		}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	send := func() (int, string) {
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	statuses := make(chan int, 2)
+	go func() { status, _ := send(); statuses <- status }()
+	<-arrived
+	go func() { status, _ := send(); statuses <- status }()
+	require.Eventually(t, func() bool { return server.GetStats().Upstreams["target"].Queued == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	// Act
+	overflowStatus, overflowBody := send()
+	close(unblock)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, overflowStatus)
+	assert.Contains(t, overflowBody, "rate limit exceeded")
+	assert.Equal(t, http.StatusOK, <-statuses)
+	assert.Equal(t, http.StatusOK, <-statuses)
+	assert.Zero(t, server.GetStats().Upstreams["target"].Queued)
+}
@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"octopus-cli/internal/config"
+)
+
+// routePrefix returns the path prefix a route matches, without any
+// trailing wildcard or slash
+func routePrefix(route config.RouteConfig) string {
+	prefix := strings.TrimSuffix(route.Path, "*")
+	return strings.TrimSuffix(prefix, "/")
+}
+
+// matchRoute returns the route with the longest path matching the start of
+// requestPath at a segment boundary
+func matchRoute(routes []config.RouteConfig, requestPath string) (config.RouteConfig, bool) {
+	var best config.RouteConfig
+	bestLen := -1
+
+	for _, route := range routes {
+		prefix := routePrefix(route)
+		if requestPath != prefix && !strings.HasPrefix(requestPath, prefix+"/") {
+			continue
+		}
+		if len(prefix) > bestLen {
+			best, bestLen = route, len(prefix)
+		}
+	}
+
+	return best, bestLen >= 0
+}
+
+// stripRoutePrefix returns a shallow copy of r with the route prefix
+// removed from its path
+func stripRoutePrefix(r *http.Request, prefix string) *http.Request {
+	stripped := r.Clone(r.Context())
+	stripped.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	if stripped.URL.Path == "" {
+		stripped.URL.Path = "/"
+	}
+	if r.URL.RawPath != "" {
+		stripped.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		if stripped.URL.RawPath == "" {
+			stripped.URL.RawPath = "/"
+		}
+	}
+	return stripped
+}
+
+// RouteDecision describes which API a request is routed to and why
+type RouteDecision struct {
+	// APIID is empty when the request goes to the active API or group
+	APIID string
+	// Reason names the rule that picked the API
+	Reason string
+	// StripPrefix is removed from the request path before forwarding
+	StripPrefix string
+}
+
+// matchModelRoute returns the first model rule whose glob matches model
+func matchModelRoute(routes []config.ModelRouteConfig, model string) (config.ModelRouteConfig, bool) {
+	if model == "" {
+		return config.ModelRouteConfig{}, false
+	}
+	for _, route := range routes {
+		if ok, err := path.Match(route.Model, model); err == nil && ok {
+			return route, true
+		}
+	}
+	return config.ModelRouteConfig{}, false
+}
+
+// decideRoute applies the routing rules in order of precedence: model rules,
+// path routes, then the API assigned to the agent
+func decideRoute(modelRoutes []config.ModelRouteConfig, routes []config.RouteConfig, agentAPI, requestPath, agent, model string) RouteDecision {
+	if route, ok := matchModelRoute(modelRoutes, model); ok {
+		return RouteDecision{APIID: route.API, Reason: fmt.Sprintf("model rule '%s'", route.Model)}
+	}
+
+	if route, ok := matchRoute(routes, requestPath); ok {
+		decision := RouteDecision{APIID: route.API, Reason: fmt.Sprintf("route '%s'", route.Path)}
+		if route.StripPrefix {
+			decision.StripPrefix = routePrefix(route)
+		}
+		return decision
+	}
+
+	if agentAPI != "" {
+		return RouteDecision{APIID: agentAPI, Reason: fmt.Sprintf("agent '%s'", agent)}
+	}
+
+	return RouteDecision{}
+}
+
+// ExplainRoute reports how the given configuration routes a request for
+// model sent to requestPath by agent. Rules are read the way the running
+// proxy reads them, skipping those that target APIs which no longer exist.
+func ExplainRoute(cfg *config.Config, requestPath, agent, model string) RouteDecision {
+	rules := NewConfigManager(cfg)
+	decision := decideRoute(rules.GetModelRoutes(), rules.GetRoutes(), rules.GetAgentAPIID(agent), requestPath, agent, model)
+	if decision.APIID != "" {
+		return decision
+	}
+
+	if len(cfg.Settings.ActiveGroup) > 0 {
+		strategy := cfg.Settings.BalanceStrategy
+		if strategy == "" {
+			strategy = BalanceRoundRobin
+		}
+		decision.Reason = fmt.Sprintf("active group (%s)", strategy)
+		return decision
+	}
+
+	decision.APIID = cfg.Settings.ActiveAPI
+	decision.Reason = "active API"
+	return decision
+}
+
+// requestModel reads the "model" field of a JSON request body. It returns
+// an empty string for bodies that are not JSON or too large to buffer.
+func requestModel(body *requestBody) string {
+	if !body.replayable() || len(body.data) == 0 {
+		return ""
+	}
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body.data, &payload); err != nil {
+		return ""
+	}
+	return payload.Model
+}
+
+// resolveTarget returns the API a request should be sent to, along with the
+// request to forward. Routing rules come first, then the active API.
+func (s *Server) resolveTarget(r *http.Request, agent string, body *requestBody) (*config.APIConfig, *http.Request, error) {
+	modelRoutes := s.configManager.GetModelRoutes()
+	model := ""
+	if len(modelRoutes) > 0 {
+		model = requestModel(body)
+	}
+
+	decision := decideRoute(modelRoutes, s.configManager.GetRoutes(), s.configManager.GetAgentAPIID(agent), r.URL.Path, agent, model)
+	if decision.APIID == "" {
+		api, err := s.getActiveAPI()
+		if err != nil {
+			return nil, nil, fmt.Errorf("no active API configured: %w", err)
+		}
+		return api, r, nil
+	}
+
+	api, err := s.configManager.GetAPI(decision.APIID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s targets an unknown API: %w", decision.Reason, err)
+	}
+
+	if s.logger != nil {
+		s.logger.Debug("Routed %s to API '%s' by %s", r.URL.Path, api.ID, decision.Reason)
+	}
+
+	if decision.StripPrefix != "" {
+		r = stripRoutePrefix(r, decision.StripPrefix)
+	}
+	return api, r, nil
+}
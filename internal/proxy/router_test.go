@@ -0,0 +1,265 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestMatchRoute_ShouldPickLongestPrefixAtSegmentBoundary(t *testing.T) {
+	routes := []config.RouteConfig{
+		{Path: "/v1/messages", API: "anthropic"},
+		{Path: "/v1/chat/completions", API: "openai"},
+		{Path: "/v1beta/models/*", API: "gemini"},
+		{Path: "/v1", API: "default-v1"},
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/v1/messages", "anthropic"},
+		{"/v1/messages/count_tokens", "anthropic"},
+		{"/v1/chat/completions", "openai"},
+		{"/v1beta/models/gemini-pro:generateContent", "gemini"},
+		{"/v1/messagesX", "default-v1"},
+		{"/v1/models", "default-v1"},
+		{"/v2/messages", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// Act
+			route, ok := matchRoute(routes, tt.path)
+
+			// Assert
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, route.API)
+		})
+	}
+}
+
+func TestStripRoutePrefix_ShouldRemoveMatchedPath(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest("POST", "/openai/v1/chat/completions?x=1", nil)
+
+	// Act
+	stripped := stripRoutePrefix(req, routePrefix(config.RouteConfig{Path: "/openai/*"}))
+
+	// Assert
+	assert.Equal(t, "/v1/chat/completions", stripped.URL.Path)
+	assert.Equal(t, "x=1", stripped.URL.RawQuery)
+	assert.Equal(t, "/openai/v1/chat/completions", req.URL.Path)
+}
+
+func TestServer_HandleRequest_WithRoutes_ShouldDispatchByPath(t *testing.T) {
+	// Arrange - each target echoes its name and the path it received
+	newTarget := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s%s", name, r.URL.Path)
+		}))
+	}
+	anthropic := newTarget("anthropic")
+	defer anthropic.Close()
+	openai := newTarget("openai")
+	defer openai.Close()
+	fallback := newTarget("active")
+	defer fallback.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "anthropic", URL: anthropic.URL},
+			{ID: "openai", URL: openai.URL},
+			{ID: "active", URL: fallback.URL},
+		},
+		Settings: config.Settings{ActiveAPI: "active"},
+		Routes: []config.RouteConfig{
+			{Path: "/v1/messages", API: "anthropic"},
+			{Path: "/openai/*", API: "openai", StripPrefix: true},
+		},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/v1/messages", "anthropic/v1/messages"},
+		{"/openai/v1/chat/completions", "openai/v1/chat/completions"},
+		{"/v1/models", "active/v1/models"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// Act
+			resp, err := http.Get(fmt.Sprintf("http://localhost:%d%s", server.GetPort(), tt.path))
+
+			// Assert
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestServer_HandleRequest_WithRouteToUnknownAPI_ShouldUseActiveAPI(t *testing.T) {
+	// Arrange
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("active"))
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:      config.ServerConfig{Port: 0},
+		APIs:        []config.APIConfig{{ID: "active", URL: targetServer.URL}},
+		Settings:    config.Settings{ActiveAPI: "active"},
+		Routes:      []config.RouteConfig{{Path: "/v1/messages", API: "missing"}},
+		ModelRoutes: []config.ModelRouteConfig{{Model: "claude-*", API: "missing"}},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), "application/json", strings.NewReader(`{"model":"claude-3"}`))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "active", string(body))
+}
+
+func TestDecideRoute_ShouldApplyRulesInPrecedenceOrder(t *testing.T) {
+	modelRoutes := []config.ModelRouteConfig{
+		{Model: "claude-opus-*", API: "official"},
+		{Model: "claude-*", API: "reseller"},
+	}
+	routes := []config.RouteConfig{{Path: "/v1/chat/completions", API: "openai"}}
+
+	tests := []struct {
+		name     string
+		path     string
+		agentAPI string
+		model    string
+		expected string
+	}{
+		{"first matching model rule wins", "/v1/messages", "", "claude-opus-4-1", "official"},
+		{"later model rule", "/v1/messages", "", "claude-haiku-4-5", "reseller"},
+		{"model rule beats path route", "/v1/chat/completions", "", "claude-haiku-4-5", "reseller"},
+		{"path route", "/v1/chat/completions", "agent-api", "gpt-4o", "openai"},
+		{"agent API", "/v1/messages", "agent-api", "gpt-4o", "agent-api"},
+		{"active API", "/v1/messages", "", "gpt-4o", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			decision := decideRoute(modelRoutes, routes, tt.agentAPI, tt.path, AgentCodex, tt.model)
+
+			// Assert
+			assert.Equal(t, tt.expected, decision.APIID)
+		})
+	}
+}
+
+func TestRequestModel_ShouldReadModelFromJSONBody(t *testing.T) {
+	// Arrange
+	body, err := bufferRequestBody(io.NopCloser(strings.NewReader(`{"model":"claude-opus-4-1","max_tokens":1}`)), 1024)
+	require.NoError(t, err)
+	notJSON, err := bufferRequestBody(io.NopCloser(strings.NewReader("model=x")), 1024)
+	require.NoError(t, err)
+
+	// Act & Assert
+	assert.Equal(t, "claude-opus-4-1", requestModel(body))
+	assert.Empty(t, requestModel(notJSON))
+	assert.Empty(t, requestModel(&requestBody{}))
+}
+
+func TestServer_HandleRequest_WithModelRoutes_ShouldRouteByModelAndKeepBody(t *testing.T) {
+	// Arrange - each target echoes its name and the body it received
+	newTarget := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s:%s", name, body)
+		}))
+	}
+	official := newTarget("official")
+	defer official.Close()
+	reseller := newTarget("reseller")
+	defer reseller.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "official", URL: official.URL},
+			{ID: "reseller", URL: reseller.URL},
+		},
+		Settings:    config.Settings{ActiveAPI: "official"},
+		ModelRoutes: []config.ModelRouteConfig{{Model: "claude-haiku-*", API: "reseller"}},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	_, haiku := postThroughProxy(t, server, `{"model":"claude-haiku-4-5"}`)
+	_, opus := postThroughProxy(t, server, `{"model":"claude-opus-4-1"}`)
+
+	// Assert
+	assert.Equal(t, `reseller:{"model":"claude-haiku-4-5"}`, haiku)
+	assert.Equal(t, `official:{"model":"claude-opus-4-1"}`, opus)
+}
+
+func TestExplainRoute_WithActiveGroup_ShouldNameStrategy(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		Settings: config.Settings{
+			ActiveGroup:     []config.GroupMember{{ID: "a"}, {ID: "b"}},
+			BalanceStrategy: BalanceLeastInFlight,
+		},
+	}
+
+	// Act
+	decision := ExplainRoute(cfg, "/v1/messages", AgentGeneric, "claude-opus-4-1")
+
+	// Assert
+	assert.Empty(t, decision.APIID)
+	assert.Equal(t, "active group (least-in-flight)", decision.Reason)
+}
+
+func TestExplainRoute_WithRulesToRemovedAPI_ShouldSkipThem(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		APIs:        []config.APIConfig{{ID: "active"}, {ID: "openai"}},
+		Settings:    config.Settings{ActiveAPI: "active", AgentAPIs: map[string]string{AgentGeneric: "removed"}},
+		ModelRoutes: []config.ModelRouteConfig{{Model: "claude-*", API: "removed"}},
+		Routes: []config.RouteConfig{
+			{Path: "/v1/messages", API: "removed"},
+			{Path: "/v1", API: "openai"},
+		},
+	}
+
+	// Act
+	decision := ExplainRoute(cfg, "/v1/messages", AgentGeneric, "claude-opus-4-1")
+	unrouted := ExplainRoute(cfg, "/other", AgentGeneric, "claude-opus-4-1")
+
+	// Assert
+	assert.Equal(t, "openai", decision.APIID)
+	assert.Equal(t, "route '/v1'", decision.Reason)
+	assert.Equal(t, "active", unrouted.APIID)
+	assert.Equal(t, "active API", unrouted.Reason)
+}
@@ -2,11 +2,12 @@ package proxy
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,23 +20,40 @@ import (
 type ServerStats struct {
 	RequestCount int64
 	ErrorCount   int64
-	StartTime    time.Time
-	Uptime       time.Duration
+	// UnauthorizedCount counts requests rejected for a missing or unknown
+	// client token
+	UnauthorizedCount int64
+	StartTime         time.Time
+	Uptime            time.Duration
+	Upstreams         map[string]UpstreamStats
+	// Clients counts requests per client token label
+	Clients map[string]int64
 }
 
 // Server represents the HTTP proxy server
 type Server struct {
-	config       *config.Config
-	port         int
-	actualPort   int
-	isRunning    bool
-	server       *http.Server
-	listener     net.Listener
-	stats        *ServerStats
-	logger       *utils.Logger
-	mu           sync.RWMutex
-	requestCount int64
-	errorCount   int64
+	config        *config.Config
+	configManager *ConfigManager
+	failover      *failoverTracker
+	breakers      *breakerSet
+	upstreams     *upstreamTracker
+	clients       *clientCounter
+	limiters      *rateLimiterSet
+	balancer      *balancer
+	transports    *transportCache
+	admin         *adminServer
+	tlsDir        string
+	port          int
+	actualPort    int
+	isRunning     bool
+	server        *http.Server
+	listeners     []net.Listener
+	stats         *ServerStats
+	logger        *utils.Logger
+	mu            sync.RWMutex
+	requestCount  int64
+	errorCount    int64
+	unauthorized  int64
 }
 
 // NewServer creates a new proxy server
@@ -48,10 +66,21 @@ func NewServer(cfg *config.Config) *Server {
 		}
 	}
 
+	upstreams := newUpstreamTracker()
+
 	return &Server{
-		config: cfg,
-		port:   cfg.Server.Port,
-		logger: logger,
+		config:        cfg,
+		configManager: NewConfigManager(cfg),
+		failover:      newFailoverTracker(),
+		breakers:      newBreakerSet(breakerSettingsFor(cfg.Settings)),
+		upstreams:     upstreams,
+		clients:       newClientCounter(),
+		limiters:      newRateLimiterSet(),
+		balancer:      newBalancer(upstreams),
+		transports:    newTransportCache(poolSettingsFor(cfg.Settings)),
+		tlsDir:        config.GetDefaultPathManager().TLSDir(),
+		port:          cfg.Server.Port,
+		logger:        logger,
 		stats: &ServerStats{
 			StartTime: time.Now(),
 		},
@@ -67,18 +96,51 @@ func (s *Server) Start() error {
 		return fmt.Errorf("server is already running")
 	}
 
-	// Create listener
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	listen, err := s.config.Server.Listeners()
 	if err != nil {
-		return fmt.Errorf("failed to listen on port %d: %w", s.port, err)
+		return err
+	}
+	if err := s.config.Server.ValidateClientTokens(); err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if s.config.Server.TLS.Enabled {
+		if tlsConfig, err = s.tlsConfig(listen); err != nil {
+			return fmt.Errorf("failed to set up TLS: %w", err)
+		}
+	}
+
+	// Create listeners
+	listeners := make([]net.Listener, 0, len(listen))
+	for _, address := range listen {
+		listener, err := listenOn(address)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		listeners = append(listeners, listener)
 	}
 
-	s.listener = listener
-	s.actualPort = listener.Addr().(*net.TCPAddr).Port
+	s.listeners = listeners
+	s.actualPort = 0
+	for _, listener := range listeners {
+		if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+			s.actualPort = addr.Port
+			break
+		}
+	}
 
 	// Log server startup
 	if s.logger != nil {
-		s.logger.Info("Starting Octopus proxy server on port %d", s.actualPort)
+		for i, listener := range listeners {
+			s.logger.Info("Starting Octopus proxy server on %s", listenerURL(listener, s.config.Server.Scheme()))
+			if !listen[i].IsLoopback() && !s.config.Server.ClientAuthEnabled() {
+				s.logger.Warn("Proxy is reachable from other machines on %s without client authentication", listener.Addr())
+			}
+		}
 	}
 
 	// Create HTTP server
@@ -86,27 +148,70 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/", s.handleRequest)
 
 	s.server = &http.Server{
-		Handler: mux,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
 	}
 
-	// Start server in goroutine
-	go func() {
-		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
-			if s.logger != nil {
-				s.logger.Error("Server error: %v", err)
+	// Serve every listener in its own goroutine
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			var err error
+			if tlsConfig != nil {
+				err = s.server.ServeTLS(listener, "", "")
+			} else {
+				err = s.server.Serve(listener)
 			}
-		}
-	}()
+			if err != nil && err != http.ErrServerClosed {
+				if s.logger != nil {
+					s.logger.Error("Server error on %s: %v", listener.Addr(), err)
+				}
+			}
+		}(listener)
+	}
+
+	// Start the loopback control channel used for live configuration changes
+	admin, err := newAdminServer(s)
+	if err != nil {
+		s.server.Close()
+		return fmt.Errorf("failed to start admin endpoint: %w", err)
+	}
+	s.admin = admin
 
 	s.isRunning = true
 
 	if s.logger != nil {
-		s.logger.Info("Octopus proxy server started successfully on port %d", s.actualPort)
+		s.logger.Info("Octopus proxy server started successfully on %d listener(s)", len(listeners))
+		s.logger.Info("Admin endpoint listening on %s", admin.Addr())
 	}
 
 	return nil
 }
 
+// tlsConfig returns the TLS settings of the listeners, serving the
+// configured certificate or else one signed by the generated local CA
+func (s *Server) tlsConfig(listen []config.Listener) (*tls.Config, error) {
+	certFile, keyFile := s.config.Server.TLS.CertFile, s.config.Server.TLS.KeyFile
+	switch {
+	case certFile == "" && keyFile == "":
+		local, err := EnsureLocalCertificates(s.tlsDir, LocalCertificateHosts(listen)...)
+		if err != nil {
+			return nil, err
+		}
+		certFile, keyFile = local.CertFile, local.KeyFile
+	case certFile == "" || keyFile == "":
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
 // Stop stops the HTTP proxy server
 func (s *Server) Stop() error {
 	s.mu.Lock()
@@ -131,6 +236,15 @@ func (s *Server) Stop() error {
 		return fmt.Errorf("failed to shutdown server: %w", err)
 	}
 
+	s.transports.closeIdle()
+
+	if s.admin != nil {
+		if err := s.admin.Close(); err != nil && s.logger != nil {
+			s.logger.Error("Failed to close admin endpoint: %v", err)
+		}
+		s.admin = nil
+	}
+
 	s.isRunning = false
 
 	if s.logger != nil {
@@ -157,14 +271,38 @@ func (s *Server) GetPort() int {
 	return s.port
 }
 
-// UpdateConfig updates the server configuration
+// AdminAddr returns the loopback address of the admin endpoint, or an empty
+// string if the server is not running
+func (s *Server) AdminAddr() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.admin == nil {
+		return ""
+	}
+	return s.admin.Addr()
+}
+
+// AdminToken returns the token clients must present to the admin endpoint
+func (s *Server) AdminToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.admin == nil {
+		return ""
+	}
+	return s.admin.token
+}
+
+// UpdateConfig updates an API configuration in place, adding it if it does
+// not exist yet. The change applies to the next request.
 func (s *Server) UpdateConfig(apiConfig *config.APIConfig) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if apiConfig == nil {
+		return fmt.Errorf("API configuration is nil")
+	}
 
-	// TODO: Implement config update logic
-	// For now, just accept any config update
-	return nil
+	if err := s.configManager.UpdateAPI(*apiConfig); err == nil {
+		return nil
+	}
+	return s.configManager.AddAPI(*apiConfig)
 }
 
 // GetStats returns current server statistics
@@ -175,7 +313,15 @@ func (s *Server) GetStats() *ServerStats {
 	stats := *s.stats
 	stats.RequestCount = atomic.LoadInt64(&s.requestCount)
 	stats.ErrorCount = atomic.LoadInt64(&s.errorCount)
+	stats.UnauthorizedCount = atomic.LoadInt64(&s.unauthorized)
 	stats.Uptime = time.Since(s.stats.StartTime)
+	stats.Upstreams = s.upstreams.snapshot()
+	for id, depth := range s.limiters.queued() {
+		upstream := stats.Upstreams[id]
+		upstream.Queued = depth
+		stats.Upstreams[id] = upstream
+	}
+	stats.Clients = s.clients.snapshot()
 	return &stats
 }
 
@@ -183,130 +329,161 @@ func (s *Server) GetStats() *ServerStats {
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 	atomic.AddInt64(&s.requestCount, 1)
 
-	// Log incoming request
+	// Only clients holding an access token may spend the upstream keys
+	client := ""
+	if tokens := s.config.Server.ClientTokens; len(tokens) > 0 {
+		label, ok := authenticateClient(r, tokens)
+		if !ok {
+			atomic.AddInt64(&s.unauthorized, 1)
+			if s.logger != nil {
+				s.logger.Warn("Rejected request: %s %s from %s without a valid client token", r.Method, r.URL.Path, r.RemoteAddr)
+			}
+			writeUnauthorized(w, r)
+			return
+		}
+		client = label
+		s.clients.add(label)
+	}
+
+	// Log incoming request with the detected agent
+	agent := detectAgent(r)
 	if s.logger != nil {
-		s.logger.Info("Incoming request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		if client != "" {
+			s.logger.Info("Incoming request: %s %s from %s (agent: %s, client: %s)", r.Method, r.URL.Path, r.RemoteAddr, agent, client)
+		} else {
+			s.logger.Info("Incoming request: %s %s from %s (agent: %s)", r.Method, r.URL.Path, r.RemoteAddr, agent)
+		}
 	}
 
-	// Get active API configuration
-	activeAPI, err := s.getActiveAPI()
+	// Buffer the body once so it can be inspected for routing and replayed
+	// against every API of the failover chain
+	body, err := bufferRequestBody(r.Body, s.replayLimit())
+	if err != nil {
+		atomic.AddInt64(&s.errorCount, 1)
+		http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Resolve the target API from the routing rules or the active API
+	activeAPI, routed, err := s.resolveTarget(r, agent, body)
 	if err != nil {
 		atomic.AddInt64(&s.errorCount, 1)
 		if s.logger != nil {
-			s.logger.Error("No active API configured: %v", err)
+			s.logger.Error("No target API for %s: %v", r.URL.Path, err)
 		}
-		http.Error(w, fmt.Sprintf("no active API configured: %v", err), http.StatusBadGateway)
+		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
+	r = routed
 
 	// Log API forwarding
 	if s.logger != nil {
 		s.logger.Info("Forwarding request to API: %s (%s)", activeAPI.ID, activeAPI.URL)
 	}
 
-	// Forward the request
-	if err := s.forwardRequest(w, r, activeAPI); err != nil {
+	// Forward the request, failing over to the fallback chain if needed
+	if err := s.forwardWithFailover(w, r, body, s.failoverChain(activeAPI)); err != nil {
 		atomic.AddInt64(&s.errorCount, 1)
 		if s.logger != nil {
 			s.logger.Error("Failed to forward request to %s: %v", activeAPI.URL, err)
 		}
+
+		// Once the upstream response has started there is nothing left to report to the client
+		var started *responseStartedError
+		if errors.As(err, &started) {
+			return
+		}
+
+		// Ask the client to back off when every API is over its rate limits
+		if errors.Is(err, errRateLimited) {
+			http.Error(w, fmt.Sprintf("failed to forward request: %v", err), http.StatusTooManyRequests)
+			return
+		}
+
+		// Fail fast while every API of the chain has an open circuit
+		if errors.Is(err, errCircuitOpen) {
+			http.Error(w, fmt.Sprintf("failed to forward request: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+
 		http.Error(w, fmt.Sprintf("failed to forward request: %v", err), http.StatusBadGateway)
 		return
 	}
 
-	// Log successful forwarding
-	if s.logger != nil {
-		s.logger.Info("Request forwarded successfully to %s", activeAPI.ID)
-	}
 }
 
-// getActiveAPI returns the currently active API configuration
+// getActiveAPI returns the API the next request should go to: a member of
+// the active group picked by the balancer, or the single active API
 func (s *Server) getActiveAPI() (*config.APIConfig, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if s.config.Settings.ActiveAPI == "" {
-		return nil, fmt.Errorf("no active API")
+	members, strategy := s.configManager.GetActiveGroup()
+	if len(members) == 0 {
+		return s.configManager.GetActiveAPI()
 	}
 
-	for _, api := range s.config.APIs {
-		if api.ID == s.config.Settings.ActiveAPI {
-			return &api, nil
+	// Leave out members whose circuit is open, unless that is all of them
+	available := make([]groupMember, 0, len(members))
+	for _, member := range members {
+		if !s.breakers.rejecting(member.api.ID) {
+			available = append(available, member)
 		}
 	}
-
-	return nil, fmt.Errorf("active API '%s' not found", s.config.Settings.ActiveAPI)
-}
-
-// forwardRequest forwards the request to the target API
-func (s *Server) forwardRequest(w http.ResponseWriter, r *http.Request, api *config.APIConfig) error {
-	// Parse target URL
-	targetURL, err := url.Parse(api.URL)
-	if err != nil {
-		return fmt.Errorf("invalid API URL: %w", err)
-	}
-
-	// Create target request
-	targetURL.Path = r.URL.Path
-	targetURL.RawQuery = r.URL.RawQuery
-
-	// Configure timeout
-	timeout := time.Duration(api.Timeout) * time.Second
-	if timeout <= 0 {
-		timeout = 30 * time.Second // default timeout
+	if len(available) == 0 {
+		available = members
 	}
 
-	// Create request with timeout context
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
-	defer cancel()
-
-	targetReq, err := http.NewRequestWithContext(ctx, r.Method, targetURL.String(), r.Body)
-	if err != nil {
-		return fmt.Errorf("failed to create target request: %w", err)
-	}
+	return s.balancer.pick(available, strategy)
+}
 
-	// Copy headers
-	for name, values := range r.Header {
-		for _, value := range values {
-			targetReq.Header.Add(name, value)
-		}
-	}
+// newForwardEngine creates a forward engine for the given API
+func (s *Server) newForwardEngine(api *config.APIConfig) *ForwardEngine {
+	engine := NewForwardEngine(api)
+	engine.SetTransport(s.transports.get(api))
+	engine.SetReplayLimit(s.replayLimit())
+	engine.SetForwardedHeaders(s.config.Settings.ForwardedHeaders)
+	return engine
+}
 
-	// Add API key if present
-	if api.APIKey != "" {
-		targetReq.Header.Set("Authorization", "Bearer "+api.APIKey)
-	}
+// forgetAPI drops the per-API state kept for an API that was removed
+func (s *Server) forgetAPI(apiID string) {
+	s.limiters.remove(apiID)
+	s.transports.remove(apiID)
+	s.breakers.remove(apiID)
+	s.failover.markHealthy(apiID)
+	s.upstreams.remove(apiID)
+}
 
-	// Make request to target (using the timeout from context)
-	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			Proxy: nil, // Disable proxy to get direct connection errors
-		},
+// replayLimit returns the largest request body buffered for retries
+func (s *Server) replayLimit() int64 {
+	if limitMB := s.config.Settings.RetryBodyLimitMB; limitMB > 0 {
+		return int64(limitMB) << 20
 	}
+	return defaultReplayLimit
+}
 
-	resp, err := client.Do(targetReq)
-	if err != nil {
-		// Don't write anything to response here - let handleRequest do it
-		return fmt.Errorf("request to target failed: %w", err)
-	}
+// relayResponse copies an upstream response to the client
+func (s *Server) relayResponse(w http.ResponseWriter, resp *http.Response) error {
 	defer resp.Body.Close()
 
-	// Copy response headers
-	for name, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(name, value)
+	// Copy end-to-end response headers
+	copyResponseHeaders(w.Header(), resp.Header)
+
+	// Streamed responses are relayed event by event
+	if isEventStream(resp) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(resp.StatusCode)
+		if err := copyEventStream(w, resp.Body); err != nil {
+			return &responseStartedError{err: fmt.Errorf("failed to relay event stream: %w", err)}
 		}
+		return nil
 	}
 
 	// Set status code
 	w.WriteHeader(resp.StatusCode)
 
 	// Copy response body
-	_, err = io.Copy(w, resp.Body)
-	if err != nil {
+	if _, err := io.Copy(w, resp.Body); err != nil {
 		// Response already started writing, can't change status code now
-		return fmt.Errorf("failed to copy response body: %w", err)
+		return &responseStartedError{err: fmt.Errorf("failed to copy response body: %w", err)}
 	}
 
 	return nil
@@ -3,6 +3,7 @@ package proxy
 import (
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -54,6 +55,40 @@ func TestServer_Start_WithValidPort_ShouldStartServer(t *testing.T) {
 	server.Stop()
 }
 
+func TestServer_Start_ShouldBindToConfiguredHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"", "127.0.0.1"},
+		{"127.0.0.1", "127.0.0.1"},
+		{"::1", "::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			// Arrange
+			if tt.host == "::1" {
+				if l, err := net.Listen("tcp", "[::1]:0"); err != nil {
+					t.Skip("IPv6 loopback is not available")
+				} else {
+					l.Close()
+				}
+			}
+			server := NewServer(&config.Config{Server: config.ServerConfig{Host: tt.host, Port: 0}})
+
+			// Act
+			err := server.Start()
+
+			// Assert
+			require.NoError(t, err)
+			defer server.Stop()
+			addr := server.listeners[0].Addr().(*net.TCPAddr)
+			assert.Equal(t, tt.expected, addr.IP.String())
+		})
+	}
+}
+
 func TestServer_Start_WhenAlreadyRunning_ShouldReturnError(t *testing.T) {
 	// Arrange
 	cfg := &config.Config{
@@ -322,7 +357,8 @@ func TestServer_HandleRequest_ShouldPreserveHeaders(t *testing.T) {
 	cfg := &config.Config{
 		Server: config.ServerConfig{Port: 0},
 		APIs: []config.APIConfig{
-			{ID: "target", URL: targetServer.URL, IsActive: true},
+			// Client credentials only pass through with the "none" auth style
+			{ID: "target", URL: targetServer.URL, IsActive: true, AuthStyle: AuthStyleNone},
 		},
 		Settings: config.Settings{ActiveAPI: "target"},
 	}
@@ -401,3 +437,83 @@ func TestServer_Graceful_Shutdown_ShouldCompleteActiveRequests(t *testing.T) {
 		t.Fatal("Request did not complete within timeout")
 	}
 }
+
+func TestServer_HandleRequest_WithPersistentUpstreamError_ShouldRelayUpstreamResponse(t *testing.T) {
+	// Arrange
+	const overloaded = `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`
+	callCount := 0
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(overloaded))
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "target", URL: targetServer.URL, RetryCount: 2},
+		},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	proxyURL := fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort())
+	resp, err := http.Post(proxyURL, "application/json", strings.NewReader(`{}`))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, overloaded, string(body))
+	assert.Equal(t, 2, callCount)
+}
+
+func TestServer_HandleRequest_WithTransientUpstreamError_ShouldRetryWithSameBody(t *testing.T) {
+	// Arrange - first attempt fails, second echoes the body
+	callCount := 0
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		if callCount == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{
+			{ID: "target", URL: targetServer.URL, RetryCount: 2},
+		},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	proxyURL := fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort())
+	resp, err := http.Post(proxyURL, "application/json", strings.NewReader(`{"test": "retry"}`))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"test": "retry"}`, string(body))
+	assert.Equal(t, 2, callCount)
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// responseStartedError marks a failure that happened after the response
+// status was already sent to the client, when no error page can be written
+type responseStartedError struct {
+	err error
+}
+
+func (e *responseStartedError) Error() string { return e.err.Error() }
+
+func (e *responseStartedError) Unwrap() error { return e.err }
+
+// isEventStream reports whether the upstream response is a server-sent event stream
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/event-stream"
+}
+
+// copyEventStream relays a server-sent event stream to the client, flushing
+// after every complete event so tokens reach the agent as soon as they arrive
+func copyEventStream(w http.ResponseWriter, body io.Reader) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		// Nothing to flush through, fall back to a plain copy
+		_, err := io.Copy(w, body)
+		return err
+	}
+
+	// Send headers right away so the client knows the stream is open
+	flusher.Flush()
+
+	reader := bufio.NewReader(body)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, err := w.Write(line); err != nil {
+				return fmt.Errorf("failed to write event to client: %w", err)
+			}
+
+			// A blank line terminates an event
+			if isBlankLine(line) {
+				flusher.Flush()
+			}
+		}
+
+		if readErr == io.EOF {
+			flusher.Flush()
+			return nil
+		}
+		if readErr != nil {
+			flusher.Flush()
+			return fmt.Errorf("failed to read event stream: %w", readErr)
+		}
+	}
+}
+
+// isBlankLine reports whether line contains only a line terminator
+func isBlankLine(line []byte) bool {
+	switch string(line) {
+	case "\n", "\r\n", "\r":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// startStreamingProxy starts a proxy server in front of the given target
+func startStreamingProxy(t *testing.T, targetURL string) *Server {
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetURL}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { server.Stop() })
+	return server
+}
+
+// readEvent reads lines until the blank line that terminates an SSE event
+func readEvent(reader *bufio.Reader) (string, error) {
+	var event strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event.String(), err
+		}
+		if line == "\n" {
+			return event.String(), nil
+		}
+		event.WriteString(line)
+	}
+}
+
+func TestServer_HandleRequest_WithEventStream_ShouldDeliverEventsOneAtATime(t *testing.T) {
+	// Arrange - upstream emits one event per release signal
+	release := make(chan struct{})
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher := w.(http.Flusher)
+		flusher.Flush()
+
+		for i := 1; i <= 3; i++ {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+			fmt.Fprintf(w, "event: content_block_delta\ndata: {\"index\":%d}\n\n", i)
+			flusher.Flush()
+		}
+	}))
+	defer targetServer.Close()
+
+	server := startStreamingProxy(t, targetServer.URL)
+
+	// Act
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Assert - every event is readable before the next one is produced
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	for i := 1; i <= 3; i++ {
+		release <- struct{}{}
+
+		eventCh := make(chan string, 1)
+		go func() {
+			event, _ := readEvent(reader)
+			eventCh <- event
+		}()
+
+		select {
+		case event := <-eventCh:
+			assert.Contains(t, event, fmt.Sprintf(`{"index":%d}`, i))
+		case <-time.After(2 * time.Second):
+			t.Fatalf("event %d was not flushed to the client", i)
+		}
+	}
+}
+
+func TestServer_HandleRequest_WithEventStream_ClientDisconnectShouldCancelUpstream(t *testing.T) {
+	// Arrange - upstream streams until its request context is cancelled
+	cancelled := make(chan struct{})
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("event: ping\ndata: {}\n\n"))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer targetServer.Close()
+
+	server := startStreamingProxy(t, targetServer.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	event, err := readEvent(bufio.NewReader(resp.Body))
+	require.NoError(t, err)
+	require.Contains(t, event, "event: ping")
+
+	// Act
+	cancel()
+
+	// Assert
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request was not cancelled after client disconnect")
+	}
+}
+
+func TestIsEventStream_ShouldMatchMediaTypeWithParameters(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"text/event-stream", true},
+		{"text/event-stream; charset=utf-8", true},
+		{"application/json", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Content-Type", tt.contentType)
+		assert.Equal(t, tt.expected, isEventStream(resp), tt.contentType)
+	}
+}
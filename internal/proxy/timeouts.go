@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// defaultTimeout applies when neither a per-phase nor the legacy timeout is set
+const defaultTimeout = 30 * time.Second
+
+// errStreamIdle is returned when an upstream body stops producing data
+var errStreamIdle = errors.New("upstream stream idle timeout exceeded")
+
+// upstreamTimeouts holds the per-phase timeouts of an upstream API
+type upstreamTimeouts struct {
+	connect   time.Duration // dial and TLS handshake
+	firstByte time.Duration // until response headers arrive
+	idle      time.Duration // maximum silence between body chunks
+}
+
+// timeoutsFor resolves the per-phase timeouts of an API configuration
+func timeoutsFor(api *config.APIConfig) upstreamTimeouts {
+	fallback := time.Duration(api.Timeout) * time.Second
+	if fallback <= 0 {
+		fallback = defaultTimeout
+	}
+
+	resolve := func(seconds int) time.Duration {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return fallback
+	}
+
+	return upstreamTimeouts{
+		connect:   resolve(api.ConnectTimeout),
+		firstByte: resolve(api.FirstByteTimeout),
+		idle:      resolve(api.IdleTimeout),
+	}
+}
+
+// newUpstreamTransport creates a pooled transport enforcing the connect and
+// first-byte timeouts. There is deliberately no whole-request deadline.
+func newUpstreamTransport(t upstreamTimeouts, pool poolSettings) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   t.connect,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:                 nil, // Disable proxy to get direct connection errors
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true, // a custom dialer disables HTTP/2 otherwise
+		TLSHandshakeTimeout:   t.connect,
+		ResponseHeaderTimeout: t.firstByte,
+		MaxIdleConns:          pool.maxIdleConns,
+		MaxIdleConnsPerHost:   pool.maxIdleConnsPerHost,
+		IdleConnTimeout:       pool.idleConnTimeout,
+	}
+}
+
+// idleTimeoutBody cancels the upstream request when no data arrives within
+// the idle timeout. Every successful read re-arms the timer.
+type idleTimeoutBody struct {
+	body    io.ReadCloser
+	idle    time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	expired bool
+}
+
+// withIdleTimeout wraps a response body so that it is aborted after idle
+// silence. cancel must cancel the context the upstream request was made with.
+func withIdleTimeout(body io.ReadCloser, idle time.Duration, cancel context.CancelFunc) io.ReadCloser {
+	b := &idleTimeoutBody{
+		body:   body,
+		idle:   idle,
+		cancel: cancel,
+	}
+	b.timer = time.AfterFunc(idle, b.expire)
+	return b
+}
+
+// expire aborts the upstream request
+func (b *idleTimeoutBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.cancel()
+}
+
+// Read reads from the upstream body and re-arms the idle timer
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.idle)
+	}
+
+	if err != nil && err != io.EOF {
+		b.mu.Lock()
+		expired := b.expired
+		b.mu.Unlock()
+		if expired {
+			return n, fmt.Errorf("%w after %s", errStreamIdle, b.idle)
+		}
+	}
+
+	return n, err
+}
+
+// Close stops the idle timer and releases the upstream request
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
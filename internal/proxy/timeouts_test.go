@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestTimeoutsFor_ShouldFallBackToLegacyTimeoutThenDefault(t *testing.T) {
+	// Arrange
+	tests := []struct {
+		name     string
+		api      config.APIConfig
+		expected upstreamTimeouts
+	}{
+		{
+			name:     "defaults",
+			api:      config.APIConfig{},
+			expected: upstreamTimeouts{connect: 30 * time.Second, firstByte: 30 * time.Second, idle: 30 * time.Second},
+		},
+		{
+			name:     "legacy timeout",
+			api:      config.APIConfig{Timeout: 45},
+			expected: upstreamTimeouts{connect: 45 * time.Second, firstByte: 45 * time.Second, idle: 45 * time.Second},
+		},
+		{
+			name:     "per-phase overrides",
+			api:      config.APIConfig{Timeout: 45, ConnectTimeout: 5, FirstByteTimeout: 120, IdleTimeout: 60},
+			expected: upstreamTimeouts{connect: 5 * time.Second, firstByte: 120 * time.Second, idle: 60 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act & Assert
+			assert.Equal(t, tt.expected, timeoutsFor(&tt.api))
+		})
+	}
+}
+
+func TestIdleTimeoutBody_WithSteadyData_ShouldNeverExpire(t *testing.T) {
+	// Arrange - data arrives every 20ms, well within the 50ms idle window
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 10; i++ {
+			time.Sleep(20 * time.Millisecond)
+			pw.Write([]byte("x"))
+		}
+		pw.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := withIdleTimeout(pr, 50*time.Millisecond, cancel)
+	defer body.Close()
+
+	// Act
+	data, err := io.ReadAll(body)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "xxxxxxxxxx", string(data))
+	assert.NoError(t, ctx.Err())
+}
+
+func TestIdleTimeoutBody_WhenStalled_ShouldCancelUpstream(t *testing.T) {
+	// Arrange - the pipe is closed with the context error once cancelled
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		pw.Write([]byte("first"))
+		<-ctx.Done()
+		pw.CloseWithError(ctx.Err())
+	}()
+
+	body := withIdleTimeout(pr, 50*time.Millisecond, cancel)
+	defer body.Close()
+
+	// Act
+	data, err := io.ReadAll(body)
+
+	// Assert
+	assert.ErrorIs(t, err, errStreamIdle)
+	assert.Equal(t, "first", string(data))
+}
+
+func TestServer_HandleRequest_WithLongRunningStream_ShouldNotBeCutOff(t *testing.T) {
+	// Arrange - the stream lasts longer than the legacy one-second timeout
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 6; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(300 * time.Millisecond)
+		}
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL, Timeout: 1}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "data: 5")
+}
+
+func TestServer_HandleRequest_WithSlowFirstByte_ShouldReturnBadGateway(t *testing.T) {
+	// Arrange - headers take longer than the first-byte timeout
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(3 * time.Second):
+		case <-r.Context().Done():
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL, FirstByteTimeout: 1}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	start := time.Now()
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
@@ -0,0 +1,360 @@
+package proxy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+	"octopus-cli/internal/utils"
+)
+
+// File names of the generated local CA and the certificate it signs
+const (
+	localCAFile      = "ca.pem"
+	localCAKeyFile   = "ca-key.pem"
+	localCertFile    = "localhost.pem"
+	localCertKeyFile = "localhost-key.pem"
+)
+
+// Lifetimes of generated certificates. Server certificates stay within the
+// 825 days Apple platforms accept and are renewed a month before expiry.
+const (
+	localCAValidity   = 10 * 365 * 24 * time.Hour
+	localCertValidity = 825 * 24 * time.Hour
+	localCertRenewal  = 30 * 24 * time.Hour
+)
+
+// LocalCertificates are the files of the generated local CA and of the
+// certificate it signs for the proxy
+type LocalCertificates struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// CAFingerprint is the SHA-256 fingerprint of the CA certificate
+	CAFingerprint string
+}
+
+// LocalCertificateHosts returns the hosts of the TCP listeners, which the
+// generated server certificate must name besides localhost
+func LocalCertificateHosts(listen []config.Listener) []string {
+	var hosts []string
+	for _, address := range listen {
+		if host, _, err := net.SplitHostPort(address.Address); err == nil && address.Network == "tcp" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// EnsureLocalCertificates makes sure dir holds a local CA and a server
+// certificate signed by it for localhost, the loopback addresses and hosts.
+// Existing files are kept, so a CA the user already trusts stays valid; the
+// server certificate is issued again when it expires soon or misses a host.
+func EnsureLocalCertificates(dir string, hosts ...string) (*LocalCertificates, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create TLS directory: %w", err)
+	}
+
+	files := &LocalCertificates{
+		CAFile:   filepath.Join(dir, localCAFile),
+		CertFile: filepath.Join(dir, localCertFile),
+		KeyFile:  filepath.Join(dir, localCertKeyFile),
+	}
+	caKeyFile := filepath.Join(dir, localCAKeyFile)
+	now := time.Now()
+
+	caCert, caKey, err := loadLocalCA(files.CAFile, caKeyFile)
+	issued := false
+	if err != nil || now.Add(localCertRenewal).After(caCert.NotAfter) {
+		if caCert, caKey, err = createLocalCA(files.CAFile, caKeyFile, now); err != nil {
+			return nil, err
+		}
+		issued = true
+	}
+
+	names := localCertNames(hosts)
+	if issued || !localCertValid(files.CertFile, caCert, names, now) {
+		if err := createLocalCert(files.CertFile, files.KeyFile, caCert, caKey, names, now); err != nil {
+			return nil, err
+		}
+	}
+
+	files.CAFingerprint = certificateFingerprint(caCert)
+	return files, nil
+}
+
+// localCertNames returns the names a generated certificate covers: the
+// loopback names plus every host that is not a wildcard address
+func localCertNames(hosts []string) []string {
+	names := []string{"localhost", "127.0.0.1", "::1"}
+	for _, host := range hosts {
+		host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(host), "["), "]")
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			continue
+		}
+		known := false
+		for _, name := range names {
+			if strings.EqualFold(name, host) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			names = append(names, host)
+		}
+	}
+	return names
+}
+
+// loadLocalCA reads the CA certificate and key
+func loadLocalCA(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	cert, err := readCertificate(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("%s holds no PEM key", keyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok || !cert.IsCA {
+		return nil, nil, fmt.Errorf("%s is not a usable CA", certFile)
+	}
+	return cert, signer, nil
+}
+
+// createLocalCA generates a CA and writes it to certFile and keyFile
+func createLocalCA(certFile, keyFile string, now time.Time) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Octopus Local CA", Organization: []string{"Octopus"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(localCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeKey(keyFile, key); err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(certFile, "CERTIFICATE", der, 0644); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// localCertValid reports whether certFile is signed by ca, covers names and
+// is not about to expire
+func localCertValid(certFile string, ca *x509.Certificate, names []string, now time.Time) bool {
+	cert, err := readCertificate(certFile)
+	if err != nil || cert.CheckSignatureFrom(ca) != nil || now.Add(localCertRenewal).After(cert.NotAfter) {
+		return false
+	}
+	for _, name := range names {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// createLocalCert issues a server certificate for names signed by the CA
+func createLocalCert(certFile, keyFile string, ca *x509.Certificate, caKey crypto.Signer, names []string, now time.Time) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate certificate key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[0], Organization: []string{"Octopus"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(localCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+	if err := writeKey(keyFile, key); err != nil {
+		return err
+	}
+	return writePEM(certFile, "CERTIFICATE", der, 0644)
+}
+
+// readCertificate parses the first certificate of a PEM file
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s holds no PEM certificate", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// writeKey stores key as a PKCS #8 PEM file readable only by the owner
+func writeKey(path string, key crypto.Signer) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %w", err)
+	}
+	return writePEM(path, "PRIVATE KEY", der, 0600)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+// certificateFingerprint formats the SHA-256 digest of cert the way
+// browsers and openssl show it
+func certificateFingerprint(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(digest))
+	for i, b := range digest {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// certReloader serves the certificate in certFile and keyFile, loading it
+// again whenever either file changes so renewed certificates apply without
+// a restart. A pair that fails to load leaves the previous one in use.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *utils.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certStamp fileStamp
+	keyStamp  fileStamp
+}
+
+func newCertReloader(certFile, keyFile string, logger *utils.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the pair if it changed since it was last loaded. The caller
+// must hold r.mu unless r is not shared yet.
+func (r *certReloader) reload() error {
+	certStamp, err := statFile(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyStamp, err := statFile(r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && certStamp == r.certStamp && keyStamp == r.keyStamp {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if r.cert != nil && r.logger != nil {
+		r.logger.Info("Reloaded TLS certificate from %s", r.certFile)
+	}
+	r.cert, r.certStamp, r.keyStamp = &cert, certStamp, keyStamp
+	return nil
+}
+
+// GetCertificate returns the current certificate for a TLS handshake
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reload(); err != nil && r.logger != nil {
+		r.logger.Warn("Keeping the current TLS certificate: %v", err)
+	}
+	return r.cert, nil
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+func TestServer_Start_WithTLSEnabled_ShouldServeCertificateOfLocalCA(t *testing.T) {
+	// Arrange
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0, TLS: config.TLSConfig{Enabled: true}},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	server.tlsDir = t.TempDir()
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	caPEM, err := os.ReadFile(filepath.Join(server.tlsDir, localCAFile))
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caPEM))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	// Act
+	resp, err := client.Get(fmt.Sprintf("https://localhost:%d/v1/models", server.GetPort()))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"ok":true}`, string(body))
+}
+
+func TestEnsureLocalCertificates_ShouldKeepCAAndReissueForNewHost(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	first, err := EnsureLocalCertificates(dir, "127.0.0.1")
+	require.NoError(t, err)
+	firstCert, err := os.ReadFile(first.CertFile)
+	require.NoError(t, err)
+
+	// Act
+	unchanged, err1 := EnsureLocalCertificates(dir, "0.0.0.0")
+	unchangedCert, _ := os.ReadFile(unchanged.CertFile)
+	reissued, err2 := EnsureLocalCertificates(dir, "octopus.lan")
+
+	// Assert
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	assert.Equal(t, first.CAFingerprint, unchanged.CAFingerprint)
+	assert.Equal(t, firstCert, unchangedCert)
+	assert.Equal(t, first.CAFingerprint, reissued.CAFingerprint)
+
+	cert, err := readCertificate(reissued.CertFile)
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("octopus.lan"))
+	assert.NoError(t, cert.VerifyHostname("::1"))
+
+	keyInfo, err := os.Stat(reissued.KeyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), keyInfo.Mode().Perm())
+}
+
+func TestCertReloader_GetCertificate_ShouldReloadChangedFiles(t *testing.T) {
+	// Arrange
+	served, err := EnsureLocalCertificates(t.TempDir())
+	require.NoError(t, err)
+	renewed, err := EnsureLocalCertificates(t.TempDir())
+	require.NoError(t, err)
+
+	reloader, err := newCertReloader(served.CertFile, served.KeyFile, nil)
+	require.NoError(t, err)
+	before, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+
+	copyFile := func(from, to string) {
+		data, err := os.ReadFile(from)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(to, data, 0600))
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(to, later, later))
+	}
+
+	// Act
+	copyFile(renewed.CertFile, served.CertFile)
+	mismatched, err1 := reloader.GetCertificate(nil)
+	copyFile(renewed.KeyFile, served.KeyFile)
+	after, err2 := reloader.GetCertificate(nil)
+
+	// Assert
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	assert.Same(t, before, mismatched, "a half-written pair keeps the previous certificate")
+	renewedCert, err := readCertificate(renewed.CertFile)
+	require.NoError(t, err)
+	assert.Equal(t, renewedCert.Raw, after.Certificate[0])
+}
+
+func TestLocalCertificateHosts_ShouldNameHostsOfTCPListeners(t *testing.T) {
+	// Arrange
+	listen := []config.Listener{
+		{Network: "tcp", Address: "127.0.0.1:8080"},
+		{Network: "unix", Address: "/tmp/octopus.sock"},
+		{Network: "tcp", Address: "[::1]:8443"},
+		{Network: "tcp", Address: "octopus.lan:9090"},
+	}
+
+	// Act
+	hosts := LocalCertificateHosts(listen)
+
+	// Assert
+	assert.Equal(t, []string{"127.0.0.1", "::1", "octopus.lan"}, hosts)
+}
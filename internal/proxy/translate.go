@@ -0,0 +1,290 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"octopus-cli/internal/config"
+)
+
+// Supported values for config.APIConfig.Protocol
+const (
+	ProtocolAnthropic = "anthropic" // Messages API, /v1/messages
+	ProtocolOpenAI    = "openai"    // Chat Completions API, /v1/chat/completions
+	ProtocolGemini    = "gemini"    // generateContent API, /v1beta/models/{model}:generateContent
+	ProtocolBedrock   = "bedrock"   // Messages on Bedrock InvokeModel, /model/{model}/invoke
+	ProtocolVertex    = "vertex"    // Messages on Vertex AI, .../publishers/anthropic/models/{model}:rawPredict
+)
+
+// inboundProtocol identifies the protocol of a client request from its
+// path. It returns an empty string for endpoints that are not translated.
+func inboundProtocol(r *http.Request) string {
+	switch {
+	case r.Method != http.MethodPost:
+		return ""
+	case strings.HasSuffix(r.URL.Path, "/v1/messages"):
+		return ProtocolAnthropic
+	case strings.HasSuffix(r.URL.Path, "/v1/chat/completions"):
+		return ProtocolOpenAI
+	case strings.HasSuffix(r.URL.Path, ":generateContent"), strings.HasSuffix(r.URL.Path, ":streamGenerateContent"):
+		return ProtocolGemini
+	}
+	return ""
+}
+
+// translator converts a request from the client's protocol to the one an
+// upstream speaks, and the upstream's response back
+type translator interface {
+	// translateRequest returns the request and body to send upstream
+	translateRequest(r *http.Request, body []byte) (*http.Request, []byte, error)
+	// translateResponse rewrites resp in place into the client's protocol
+	translateResponse(resp *http.Response) error
+}
+
+// translators holds the supported conversions, keyed by inbound protocol
+// and then by upstream protocol. Each creates a translator for one request
+// to the given API.
+var translators = map[string]map[string]func(api *config.APIConfig) translator{
+	ProtocolAnthropic: {
+		ProtocolOpenAI:  func(*config.APIConfig) translator { return &anthropicToOpenAI{} },
+		ProtocolGemini:  func(*config.APIConfig) translator { return &anthropicToGemini{} },
+		ProtocolBedrock: func(*config.APIConfig) translator { return &anthropicToBedrock{} },
+		ProtocolVertex:  func(api *config.APIConfig) translator { return &anthropicToVertex{api: api} },
+	},
+}
+
+// providerAdapter is implemented by providers whose endpoints differ from
+// the protocol they speak. It adapts requests after any translation, and
+// responses before it.
+type providerAdapter interface {
+	adaptRequest(r *http.Request, body []byte, api *config.APIConfig) (*http.Request, error)
+	adaptResponse(resp *http.Response) error
+}
+
+// adaptedTranslator runs a provider adapter around an optional translator
+type adaptedTranslator struct {
+	inner   translator
+	adapter providerAdapter
+	api     *config.APIConfig
+}
+
+func (t *adaptedTranslator) translateRequest(r *http.Request, body []byte) (*http.Request, []byte, error) {
+	if t.inner != nil {
+		var err error
+		if r, body, err = t.inner.translateRequest(r, body); err != nil {
+			return nil, nil, err
+		}
+	}
+	adapted, err := t.adapter.adaptRequest(r, body, t.api)
+	return adapted, body, err
+}
+
+func (t *adaptedTranslator) translateResponse(resp *http.Response) error {
+	if err := t.adapter.adaptResponse(resp); err != nil {
+		return err
+	}
+	if t.inner != nil {
+		return t.inner.translateResponse(resp)
+	}
+	return nil
+}
+
+// translatorFor returns the translator needed to send r to api, or nil
+// when the request can be forwarded as it is
+func translatorFor(r *http.Request, api *config.APIConfig) (translator, error) {
+	tr, err := protocolTranslator(r, api)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := ProviderFor(api)
+	if err != nil {
+		return nil, err
+	}
+	if adapter, ok := provider.(providerAdapter); ok {
+		return &adaptedTranslator{inner: tr, adapter: adapter, api: api}, nil
+	}
+	return tr, nil
+}
+
+// protocolTranslator returns the translator between the protocol of r and
+// the one of api, or nil when they match
+func protocolTranslator(r *http.Request, api *config.APIConfig) (translator, error) {
+	inbound := inboundProtocol(r)
+	upstream := apiProtocol(api)
+	if upstream == "" || inbound == "" || inbound == upstream {
+		return nil, nil
+	}
+
+	newTranslator, ok := translators[inbound][upstream]
+	if !ok {
+		return nil, fmt.Errorf("API '%s' speaks '%s' and cannot serve '%s' requests", api.ID, upstream, inbound)
+	}
+	return newTranslator(api), nil
+}
+
+// translateRequest converts r and its body for api. The returned request
+// and body are the originals when no translation is needed.
+func (s *Server) translateRequest(r *http.Request, body *requestBody, api *config.APIConfig) (*http.Request, *requestBody, translator, error) {
+	tr, err := translatorFor(r, api)
+	if err != nil || tr == nil {
+		return r, body, nil, err
+	}
+	if !body.replayable() {
+		return nil, nil, nil, fmt.Errorf("request body is too large to translate for API '%s'", api.ID)
+	}
+
+	translated, data, err := tr.translateRequest(r, body.data)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to translate request for API '%s': %w", api.ID, err)
+	}
+
+	if inbound := inboundProtocol(r); s.logger != nil && inbound != "" && inbound != apiProtocol(api) {
+		s.logger.Debug("Translating %s request for %s API '%s'", inbound, apiProtocol(api), api.ID)
+	}
+	return translated, &requestBody{data: data}, tr, nil
+}
+
+// withTranslatedPath returns a copy of r sent to a different endpoint,
+// replacing the suffix from of its path with to. Protocol-specific
+// headers of the client are dropped.
+func withTranslatedPath(r *http.Request, from, to string) *http.Request {
+	translated := r.Clone(r.Context())
+	translated.URL.Path = strings.TrimSuffix(r.URL.Path, from) + to
+	translated.URL.RawPath = ""
+	translated.ContentLength = -1
+	translated.Header.Del("Content-Length")
+	translated.Header.Set("Content-Type", "application/json")
+	for name := range translated.Header {
+		if strings.HasPrefix(name, "Anthropic-") || strings.HasPrefix(name, "Openai-") {
+			translated.Header.Del(name)
+		}
+	}
+	return translated
+}
+
+// replaceJSONBody swaps the body of resp for data
+func replaceJSONBody(resp *http.Response, data []byte) {
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+}
+
+// readResponseBody reads and closes the body of resp
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return data, nil
+}
+
+// upstreamErrorMessage extracts the message of an upstream error body.
+// OpenAI and Gemini nest it under "error", which some compatible servers
+// send as a plain string.
+func upstreamErrorMessage(data []byte, status int) string {
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(data, &payload); err == nil {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(payload.Error, &detail) == nil && detail.Message != "":
+			return detail.Message
+		case json.Unmarshal(payload.Error, &text) == nil && text != "":
+			return text
+		case payload.Message != "":
+			return payload.Message
+		}
+	}
+
+	if text := strings.TrimSpace(string(data)); text != "" {
+		return text
+	}
+	return http.StatusText(status)
+}
+
+// sseEvent encodes a server-sent event with a JSON payload
+func sseEvent(event string, payload interface{}) []byte {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		data = []byte(`{}`)
+	}
+
+	var buf bytes.Buffer
+	if event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(event)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	return buf.Bytes()
+}
+
+// eventStreamConverter re-encodes an upstream event stream in another
+// protocol. Every data payload is handed to convert, which returns the
+// events to send in its place; finish is called once at the end of the
+// upstream stream to close the converted one.
+type eventStreamConverter struct {
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	convert  func(data []byte) []byte
+	finish   func() []byte
+	pending  []byte
+	err      error
+	finished bool
+}
+
+// newEventStreamConverter wraps an event stream body with a converter
+func newEventStreamConverter(body io.ReadCloser, convert func([]byte) []byte, finish func() []byte) *eventStreamConverter {
+	return &eventStreamConverter{
+		body:    body,
+		reader:  bufio.NewReader(body),
+		convert: convert,
+		finish:  finish,
+	}
+}
+
+func (c *eventStreamConverter) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.err != nil {
+			if c.err == io.EOF && !c.finished {
+				c.finished = true
+				c.pending = c.finish()
+				continue
+			}
+			return 0, c.err
+		}
+
+		line, err := c.reader.ReadBytes('\n')
+		c.err = err
+		if bytes.HasPrefix(line, []byte("data:")) {
+			payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+			if len(payload) > 0 {
+				c.pending = c.convert(payload)
+			}
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *eventStreamConverter) Close() error {
+	return c.body.Close()
+}
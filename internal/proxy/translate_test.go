@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files under testdata")
+
+// translationCase is a golden-file translation test. Its directory under
+// testdata/translate holds:
+//
+//	request.json            what the client sends
+//	upstream_request.json   golden: what the upstream must receive
+//	upstream_response.*     what the fake upstream answers, .json or .sse
+//	response.*              golden: what the client must receive
+type translationCase struct {
+	dir string
+	// path is the client request path
+	path string
+	// status is the fake upstream's status code
+	status int
+	// upstreamPath is the path the upstream must be called on
+	upstreamPath string
+}
+
+// runTranslationCase sends the case's request through a proxy in front of
+// an upstream speaking protocol and compares both ends with the golden files
+func runTranslationCase(t *testing.T, protocol string, tc translationCase) {
+	t.Helper()
+	dir := filepath.Join("testdata", "translate", tc.dir)
+
+	responseFile, contentType := "upstream_response.json", "application/json"
+	if _, err := os.Stat(filepath.Join(dir, "upstream_response.sse")); err == nil {
+		responseFile, contentType = "upstream_response.sse", "text/event-stream"
+	}
+	upstreamResponse := readTestdata(t, filepath.Join(dir, responseFile))
+
+	type upstreamCall struct {
+		path string
+		body []byte
+	}
+	calls := make(chan upstreamCall, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls <- upstreamCall{path: r.URL.Path, body: body}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(tc.status)
+		w.Write(upstreamResponse)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: targetServer.URL, Protocol: protocol}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d%s", server.GetPort(), tc.path), "application/json",
+		bytes.NewReader(readTestdata(t, filepath.Join(dir, "request.json"))))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, tc.status, resp.StatusCode)
+
+	call := <-calls
+	assert.Equal(t, tc.upstreamPath, call.path)
+	assertGoldenJSON(t, filepath.Join(dir, "upstream_request.json"), call.body)
+
+	if strings.HasSuffix(responseFile, ".sse") {
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assertGolden(t, filepath.Join(dir, "response.sse"), body)
+	} else {
+		assertGoldenJSON(t, filepath.Join(dir, "response.json"), body)
+	}
+}
+
+func readTestdata(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+// assertGolden compares actual with a golden file, or rewrites the file
+// when the tests run with -update
+func assertGolden(t *testing.T, path string, actual []byte) {
+	t.Helper()
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, actual, 0644))
+		return
+	}
+	assert.Equal(t, string(readTestdata(t, path)), string(actual), path)
+}
+
+// assertGoldenJSON compares JSON documents regardless of formatting. Golden
+// files are stored indented for review.
+func assertGoldenJSON(t *testing.T, path string, actual []byte) {
+	t.Helper()
+	if *updateGolden {
+		var indented bytes.Buffer
+		require.NoError(t, json.Indent(&indented, actual, "", "  "))
+		indented.WriteByte('\n')
+		require.NoError(t, os.WriteFile(path, indented.Bytes(), 0644))
+		return
+	}
+	assert.JSONEq(t, string(readTestdata(t, path)), string(actual), path)
+}
+
+func TestTranslatorFor_ShouldOnlyTranslateBetweenDifferentProtocols(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		protocol  string
+		translate bool
+		wantErr   bool
+	}{
+		{"no protocol", "POST", "/v1/messages", "", false, false},
+		{"same protocol", "POST", "/v1/messages", ProtocolAnthropic, false, false},
+		{"anthropic to openai", "POST", "/v1/messages", ProtocolOpenAI, true, false},
+		{"prefixed path", "POST", "/gw/v1/messages", ProtocolOpenAI, true, false},
+		{"untranslated endpoint", "POST", "/v1/messages/count_tokens", ProtocolOpenAI, false, false},
+		{"not a post", "GET", "/v1/messages", ProtocolOpenAI, false, false},
+		{"unsupported direction", "POST", "/v1/chat/completions", ProtocolAnthropic, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+
+			// Act
+			tr, err := translatorFor(req, &config.APIConfig{ID: "target", Protocol: tt.protocol})
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.translate, tr != nil)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"octopus-cli/internal/config"
+)
+
+// Connection pool defaults used when a limit is not configured
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// poolSettings holds the connection pool limits of upstream transports
+type poolSettings struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+}
+
+// poolSettingsFor resolves the configured pool limits, applying defaults
+func poolSettingsFor(settings config.Settings) poolSettings {
+	pool := poolSettings{
+		maxIdleConns:        defaultMaxIdleConns,
+		maxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		idleConnTimeout:     defaultIdleConnTimeout,
+	}
+
+	if settings.MaxIdleConns > 0 {
+		pool.maxIdleConns = settings.MaxIdleConns
+	}
+	if settings.MaxIdleConnsPerHost > 0 {
+		pool.maxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	}
+	if settings.IdleConnTimeout > 0 {
+		pool.idleConnTimeout = time.Duration(settings.IdleConnTimeout) * time.Second
+	}
+
+	return pool
+}
+
+// transportKey captures everything an upstream transport is built from.
+// A transport is rebuilt only when its key changes.
+type transportKey struct {
+	timeouts upstreamTimeouts
+	pool     poolSettings
+}
+
+// cachedTransport is a transport together with the key it was built from
+type cachedTransport struct {
+	key       transportKey
+	transport *http.Transport
+}
+
+// transportCache keeps one pooled transport per API ID so keep-alive
+// connections, TLS sessions and HTTP/2 streams are shared across requests
+type transportCache struct {
+	mu         sync.Mutex
+	pool       poolSettings
+	transports map[string]*cachedTransport
+}
+
+// newTransportCache creates an empty transport cache with the given limits
+func newTransportCache(pool poolSettings) *transportCache {
+	return &transportCache{
+		pool:       pool,
+		transports: make(map[string]*cachedTransport),
+	}
+}
+
+// get returns the transport of an API, building it on first use or when
+// the API's timeouts changed since it was built
+func (c *transportCache) get(api *config.APIConfig) *http.Transport {
+	key := transportKey{timeouts: timeoutsFor(api), pool: c.pool}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.transports[api.ID]
+	if ok && cached.key == key {
+		return cached.transport
+	}
+	if ok {
+		// Requests still using the old transport keep their connections
+		cached.transport.CloseIdleConnections()
+	}
+
+	transport := newUpstreamTransport(key.timeouts, key.pool)
+	c.transports[api.ID] = &cachedTransport{key: key, transport: transport}
+	return transport
+}
+
+// remove drops the transport of an API, closing its idle connections.
+// Requests still using it keep their connections.
+func (c *transportCache) remove(apiID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.transports[apiID]; ok {
+		cached.transport.CloseIdleConnections()
+		delete(c.transports, apiID)
+	}
+}
+
+// closeIdle closes the idle connections of every cached transport
+func (c *transportCache) closeIdle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cached := range c.transports {
+		cached.transport.CloseIdleConnections()
+	}
+}
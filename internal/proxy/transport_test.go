@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// newConnCountingTarget creates a TLS target that counts new connections,
+// i.e. handshakes
+func newConnCountingTarget(conns *int64) *httptest.Server {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "ok")
+	}))
+	target.EnableHTTP2 = true
+	target.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(conns, 1)
+		}
+	}
+	target.StartTLS()
+	return target
+}
+
+// trustTarget makes a transport trust the target's test certificate
+func trustTarget(transport *http.Transport, target *httptest.Server) {
+	transport.TLSClientConfig = target.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+}
+
+func TestTransportCache_Get_WithUnchangedConfig_ShouldReuseTransport(t *testing.T) {
+	// Arrange
+	cache := newTransportCache(poolSettingsFor(config.Settings{}))
+	api := &config.APIConfig{ID: "api", URL: "https://example.com", Timeout: 30}
+
+	// Act
+	first := cache.get(api)
+	second := cache.get(&config.APIConfig{ID: "api", URL: "https://example.com", Timeout: 30, APIKey: "other"})
+
+	// Assert
+	assert.Same(t, first, second)
+	assert.True(t, first.ForceAttemptHTTP2)
+	assert.Equal(t, defaultMaxIdleConnsPerHost, first.MaxIdleConnsPerHost)
+}
+
+func TestTransportCache_Get_WithChangedTimeouts_ShouldRebuildTransport(t *testing.T) {
+	// Arrange
+	cache := newTransportCache(poolSettingsFor(config.Settings{}))
+	api := &config.APIConfig{ID: "api", Timeout: 30}
+	first := cache.get(api)
+
+	// Act
+	api.ConnectTimeout = 5
+	second := cache.get(api)
+
+	// Assert
+	assert.NotSame(t, first, second)
+	assert.Same(t, second, cache.get(api))
+	assert.NotSame(t, second, cache.get(&config.APIConfig{ID: "other", Timeout: 30, ConnectTimeout: 5}))
+}
+
+func TestTransportCache_Remove_ShouldDropTransportOfAPI(t *testing.T) {
+	// Arrange
+	cache := newTransportCache(poolSettingsFor(config.Settings{}))
+	api := &config.APIConfig{ID: "api", Timeout: 30}
+	first := cache.get(api)
+
+	// Act
+	cache.remove("api")
+
+	// Assert
+	assert.NotContains(t, cache.transports, "api")
+	assert.NotSame(t, first, cache.get(api))
+}
+
+func TestPoolSettingsFor_WithConfiguredLimits_ShouldApplyThem(t *testing.T) {
+	// Arrange
+	settings := config.Settings{MaxIdleConns: 10, MaxIdleConnsPerHost: 4, IdleConnTimeout: 15}
+
+	// Act
+	transport := newUpstreamTransport(timeoutsFor(&config.APIConfig{}), poolSettingsFor(settings))
+
+	// Assert
+	assert.Equal(t, 10, transport.MaxIdleConns)
+	assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 15*time.Second, transport.IdleConnTimeout)
+}
+
+func TestServer_HandleRequest_WithSequentialRequests_ShouldReuseConnection(t *testing.T) {
+	// Arrange
+	var conns int64
+	target := newConnCountingTarget(&conns)
+	defer target.Close()
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{Port: 0},
+		APIs:     []config.APIConfig{{ID: "target", URL: target.URL}},
+		Settings: config.Settings{ActiveAPI: "target"},
+	}
+	server := NewServer(cfg)
+	api, err := server.getActiveAPI()
+	require.NoError(t, err)
+	trustTarget(server.transports.get(api), target)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	// Act
+	for i := 0; i < 5; i++ {
+		status, _ := postThroughProxy(t, server, "x")
+		require.Equal(t, http.StatusOK, status)
+	}
+
+	// Assert - a single handshake serves every request
+	assert.Equal(t, int64(1), atomic.LoadInt64(&conns))
+}
+
+// benchmarkForward sends concurrent requests through engines built by
+// newEngine and reports the handshakes per request
+func benchmarkForward(b *testing.B, newEngine func(api *config.APIConfig, target *httptest.Server) *ForwardEngine) {
+	var conns int64
+	target := newConnCountingTarget(&conns)
+	defer target.Close()
+	api := &config.APIConfig{ID: "target", URL: target.URL}
+
+	// Build once up front so any shared setup happens before going parallel
+	newEngine(api, target)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"m"}`))
+			resp, err := newEngine(api, target).ForwardRequest(req.Context(), req)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	})
+	b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "handshakes/op")
+}
+
+func BenchmarkForward_TransportPerRequest(b *testing.B) {
+	benchmarkForward(b, func(api *config.APIConfig, target *httptest.Server) *ForwardEngine {
+		transport := newUpstreamTransport(timeoutsFor(api), poolSettingsFor(config.Settings{}))
+		trustTarget(transport, target)
+		engine := NewForwardEngine(api)
+		engine.SetTransport(transport)
+		return engine
+	})
+}
+
+func BenchmarkForward_PooledTransport(b *testing.B) {
+	cache := newTransportCache(poolSettingsFor(config.Settings{}))
+	benchmarkForward(b, func(api *config.APIConfig, target *httptest.Server) *ForwardEngine {
+		transport := cache.get(api)
+		if transport.TLSClientConfig == nil {
+			trustTarget(transport, target)
+		}
+		engine := NewForwardEngine(api)
+		engine.SetTransport(transport)
+		return engine
+	})
+}
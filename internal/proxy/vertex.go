@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"octopus-cli/internal/config"
+)
+
+// vertexAnthropicVersion replaces the anthropic-version header on Vertex
+const vertexAnthropicVersion = "vertex-2023-10-16"
+
+// vertexRegion returns the Vertex location of api, falling back to the
+// CLOUD_ML_REGION environment variable
+func vertexRegion(api *config.APIConfig) (string, error) {
+	if api.Region != "" {
+		return api.Region, nil
+	}
+	if region := os.Getenv("CLOUD_ML_REGION"); region != "" {
+		return region, nil
+	}
+	return "", fmt.Errorf("no region configured for API '%s'", api.ID)
+}
+
+// vertexProject returns the Google Cloud project of api, defaulting to the
+// project of its service account
+func vertexProject(api *config.APIConfig) (string, error) {
+	if api.ProjectID != "" {
+		return api.ProjectID, nil
+	}
+	path, err := googleCredentialsFile(api)
+	if err != nil {
+		return "", err
+	}
+	account, err := readServiceAccount(path)
+	if err != nil {
+		return "", err
+	}
+	if account.ProjectID == "" {
+		return "", fmt.Errorf("no project_id configured for API '%s'", api.ID)
+	}
+	return account.ProjectID, nil
+}
+
+// vertexEndpoint returns the regional Vertex AI endpoint. The global
+// location has no regional host.
+func vertexEndpoint(region string) string {
+	if region == "global" {
+		return "https://aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com", region)
+}
+
+// anthropicToVertex sends Anthropic Messages requests to Claude on Vertex
+// AI. The body stays in the Messages format, with the model moved into the
+// rawPredict path.
+type anthropicToVertex struct {
+	api *config.APIConfig
+}
+
+func (t *anthropicToVertex) translateRequest(r *http.Request, body []byte) (*http.Request, []byte, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, nil, fmt.Errorf("invalid Anthropic request: %w", err)
+	}
+
+	var model string
+	if err := json.Unmarshal(payload["model"], &model); err != nil || model == "" {
+		return nil, nil, fmt.Errorf("request names no model")
+	}
+	var stream bool
+	if raw, ok := payload["stream"]; ok {
+		if err := json.Unmarshal(raw, &stream); err != nil {
+			return nil, nil, fmt.Errorf("invalid stream field: %w", err)
+		}
+	}
+	delete(payload, "model")
+	if _, ok := payload["anthropic_version"]; !ok {
+		payload["anthropic_version"] = json.RawMessage(`"` + vertexAnthropicVersion + `"`)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project, err := vertexProject(t.api)
+	if err != nil {
+		return nil, nil, err
+	}
+	region, err := vertexRegion(t.api)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method := "rawPredict"
+	if stream {
+		method = "streamRawPredict"
+	}
+	translated := withTranslatedPath(r, "/v1/messages",
+		fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/anthropic/models/%s:%s", project, region, model, method))
+	translated.URL.RawQuery = ""
+	// Vertex takes beta flags in the usual header
+	for _, beta := range r.Header.Values("Anthropic-Beta") {
+		translated.Header.Add("Anthropic-Beta", beta)
+	}
+	return translated, data, nil
+}
+
+// translateResponse converts Google-style errors. Successful responses
+// already are Anthropic messages or events.
+func (t *anthropicToVertex) translateResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	data, err := readResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	// Some Vertex errors come wrapped in a single-element array
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var wrapped []json.RawMessage
+		if json.Unmarshal(data, &wrapped) == nil && len(wrapped) > 0 {
+			data = wrapped[0]
+		}
+	}
+
+	converted, err := json.Marshal(anthropicError(anthropicErrorType(resp.StatusCode), upstreamErrorMessage(data, resp.StatusCode)))
+	if err != nil {
+		return err
+	}
+	replaceJSONBody(resp, converted)
+	return nil
+}
+
+// vertexProvider talks to Claude on Google Vertex AI, authenticating with
+// access tokens minted from a service account
+type vertexProvider struct {
+	anthropicProvider
+	tokens *googleTokenCache
+}
+
+// BaseURL defaults to the Vertex AI endpoint of the API's region
+func (p vertexProvider) BaseURL(api *config.APIConfig) (string, error) {
+	if api.URL != "" {
+		return api.URL, nil
+	}
+	region, err := vertexRegion(api)
+	if err != nil {
+		return "", err
+	}
+	return vertexEndpoint(region), nil
+}
+
+func (p vertexProvider) ApplyAuth(req *http.Request, api *config.APIConfig) error {
+	token, err := p.tokens.token(req.Context(), api)
+	if err != nil {
+		return err
+	}
+	stripClientCredentials(req)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// ModelsURL lists the Anthropic models published on Vertex AI
+func (p vertexProvider) ModelsURL(api *config.APIConfig) (string, error) {
+	base, err := p.BaseURL(api)
+	if err != nil {
+		return "", err
+	}
+	target, err := joinURL(base, &url.URL{Path: "/v1beta1/publishers/anthropic/models"})
+	if err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+func (p vertexProvider) ParseModels(body []byte) ([]string, error) {
+	var listing struct {
+		PublisherModels []struct {
+			Name string `json:"name"`
+		} `json:"publisherModels"`
+	}
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil, fmt.Errorf("invalid model listing: %w", err)
+	}
+
+	models := make([]string, 0, len(listing.PublisherModels))
+	for _, model := range listing.PublisherModels {
+		models = append(models, strings.TrimPrefix(model.Name, "publishers/anthropic/models/"))
+	}
+	return models, nil
+}
@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"octopus-cli/internal/config"
+)
+
+// fakeTokenEndpoint stands in for Google's OAuth endpoint. It checks the
+// JWT grant against the service account's public key and counts mints.
+type fakeTokenEndpoint struct {
+	*httptest.Server
+	mints int32
+}
+
+func newFakeTokenEndpoint(t *testing.T, key *rsa.PrivateKey) *fakeTokenEndpoint {
+	endpoint := &fakeTokenEndpoint{}
+	endpoint.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		var claims struct {
+			Iss   string `json:"iss"`
+			Aud   string `json:"aud"`
+			Scope string `json:"scope"`
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, json.Unmarshal(payload, &claims))
+		assert.Equal(t, "octopus@test-project.iam.gserviceaccount.com", claims.Iss)
+		assert.Equal(t, endpoint.URL+"/token", claims.Aud)
+		assert.Equal(t, googleCloudScope, claims.Scope)
+
+		n := atomic.AddInt32(&endpoint.mints, 1)
+		fmt.Fprintf(w, `{"access_token":"ya29.token-%d","expires_in":3600,"token_type":"Bearer"}`, n)
+	}))
+	return endpoint
+}
+
+// writeServiceAccount stores a service-account key for key and returns its path
+func writeServiceAccount(t *testing.T, key *rsa.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	account, err := json.Marshal(googleServiceAccount{
+		Type:         "service_account",
+		ProjectID:    "test-project",
+		PrivateKeyID: "key-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "octopus@test-project.iam.gserviceaccount.com",
+		TokenURI:     "https://oauth2.invalid/token",
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	require.NoError(t, os.WriteFile(path, account, 0600))
+	return path
+}
+
+func TestServer_HandleRequest_WithVertexProvider_ShouldMintTokenOnceAndCallRawPredict(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tokenEndpoint := newFakeTokenEndpoint(t, key)
+	defer tokenEndpoint.Close()
+
+	type upstreamCall struct {
+		path, auth, beta string
+		body             []byte
+	}
+	calls := make(chan upstreamCall, 2)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls <- upstreamCall{path: r.URL.Path, auth: r.Header.Get("Authorization"), beta: r.Header.Get("Anthropic-Beta"), body: body}
+		fmt.Fprint(w, `{"id":"msg_vrtx_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":9,"output_tokens":2}}`)
+	}))
+	defer targetServer.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 0},
+		APIs: []config.APIConfig{{
+			ID:              "vertex",
+			URL:             targetServer.URL,
+			Provider:        ProviderVertex,
+			Region:          "us-east5",
+			CredentialsFile: writeServiceAccount(t, key),
+			TokenURL:        tokenEndpoint.URL + "/token",
+			ModelMap:        map[string]string{"claude-sonnet-4-20250514": "claude-sonnet-4@20250514"},
+		}},
+		Settings: config.Settings{ActiveAPI: "vertex"},
+	}
+	server := NewServer(cfg)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	send := func(body string) (int, string) {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/v1/messages", server.GetPort()), strings.NewReader(body))
+		req.Header.Set("X-Api-Key", "dummy")
+		req.Header.Set("Anthropic-Beta", "interleaved-thinking-2025-05-14")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	// Act
+	status, body := send(`{"model":"claude-sonnet-4-20250514","max_tokens":64,"messages":[{"role":"user","content":"Hello"}]}`)
+	send(`{"model":"claude-sonnet-4-20250514","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"msg_vrtx_1"`)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenEndpoint.mints))
+
+	first, second := <-calls, <-calls
+	assert.Equal(t, "/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict", first.path)
+	assert.Equal(t, "/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict", second.path)
+	assert.Equal(t, "Bearer ya29.token-1", first.auth)
+	assert.Equal(t, "Bearer ya29.token-1", second.auth)
+	assert.Equal(t, "interleaved-thinking-2025-05-14", first.beta)
+	assert.JSONEq(t, `{"anthropic_version":"vertex-2023-10-16","max_tokens":64,"messages":[{"role":"user","content":"Hello"}]}`, string(first.body))
+	assert.JSONEq(t, `{"anthropic_version":"vertex-2023-10-16","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"Hello"}]}`, string(second.body))
+}
+
+func TestGoogleTokenCache_Token_ShouldRefreshBeforeExpiry(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tokenEndpoint := newFakeTokenEndpoint(t, key)
+	defer tokenEndpoint.Close()
+
+	now := time.Now()
+	cache := newGoogleTokenCache()
+	cache.now = func() time.Time { return now }
+	api := &config.APIConfig{ID: "vertex", CredentialsFile: writeServiceAccount(t, key), TokenURL: tokenEndpoint.URL + "/token"}
+
+	// Act
+	first, err1 := cache.token(context.Background(), api)
+	now = now.Add(50 * time.Minute)
+	cached, err2 := cache.token(context.Background(), api)
+	now = now.Add(6 * time.Minute)
+	refreshed, err3 := cache.token(context.Background(), api)
+
+	// Assert
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.NoError(t, err3)
+	assert.Equal(t, "ya29.token-1", first)
+	assert.Equal(t, "ya29.token-1", cached)
+	assert.Equal(t, "ya29.token-2", refreshed)
+}
+
+func TestGoogleTokenCache_Token_WithoutExpiresIn_ShouldReuseToken(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	var mints int32
+	tokenEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"ya29.token-%d","token_type":"Bearer"}`, atomic.AddInt32(&mints, 1))
+	}))
+	defer tokenEndpoint.Close()
+
+	cache := newGoogleTokenCache()
+	api := &config.APIConfig{ID: "vertex", CredentialsFile: writeServiceAccount(t, key), TokenURL: tokenEndpoint.URL}
+
+	// Act
+	first, err1 := cache.token(context.Background(), api)
+	second, err2 := cache.token(context.Background(), api)
+
+	// Assert
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	assert.Equal(t, "ya29.token-1", first)
+	assert.Equal(t, first, second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mints))
+}
+
+func TestGoogleTokenCache_Token_WithSlowTokenEndpoint_ShouldNotBlockOtherAccounts(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	unblock := make(chan struct{})
+	slowEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+		w.Write([]byte(`{"access_token":"ya29.slow","expires_in":3600}`))
+	}))
+	defer slowEndpoint.Close()
+	defer close(unblock)
+	fastEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"ya29.fast","expires_in":3600}`))
+	}))
+	defer fastEndpoint.Close()
+
+	cache := newGoogleTokenCache()
+	credentials := writeServiceAccount(t, key)
+	slow := &config.APIConfig{ID: "slow", CredentialsFile: credentials, TokenURL: slowEndpoint.URL}
+	fast := &config.APIConfig{ID: "fast", CredentialsFile: credentials, TokenURL: fastEndpoint.URL}
+
+	slowCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cache.token(slowCtx, slow)
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 1
+	}, time.Second, time.Millisecond)
+
+	// Act
+	token, err := cache.token(context.Background(), fast)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "ya29.fast", token)
+}
+
+func TestAnthropicToVertex_TranslateResponse_ShouldConvertGoogleErrors(t *testing.T) {
+	// Arrange
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`[{"error":{"code":429,"message":"Quota exceeded for aiplatform.googleapis.com","status":"RESOURCE_EXHAUSTED"}}]`)),
+	}
+
+	// Act
+	err := (&anthropicToVertex{}).translateResponse(resp)
+
+	// Assert
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"type":"error","error":{"type":"rate_limit_error","message":"Quota exceeded for aiplatform.googleapis.com"}}`, string(body))
+}
+
+func TestVertexProvider_BaseURL_ShouldDefaultToRegionalEndpoint(t *testing.T) {
+	tests := []struct {
+		region   string
+		expected string
+	}{
+		{"us-east5", "https://us-east5-aiplatform.googleapis.com"},
+		{"global", "https://aiplatform.googleapis.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			// Act
+			baseURL, err := vertexProvider{}.BaseURL(&config.APIConfig{Region: tt.region})
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, baseURL)
+		})
+	}
+}